@@ -57,6 +57,8 @@ func doMain() (err error) {
 			sprintcore.BoltStoreFactory("config-store"),
 			sprintcore.BadgerStoreFactory("secure-store"),
 			sprintcore.AutoupdateService(),
+			sprintcore.StoreJobLock(),
+			sprintcore.StoreTokenRevocationList(),
 			sprintcore.LumberjackFactory(),
 
 			glue.Child(sprint.ServerRole,
@@ -65,6 +67,7 @@ func doMain() (err error) {
 				sprintserver.HttpServerFactory("control-gateway-server"),
 				//sprintserver.TlsConfigFactory("tls-config"),
 				sprintserver.TemplatePage("/", "resources:templates/index.tmpl"),
+				sprintserver.JwksPage("/.well-known/jwks.json"),
 				),
 
 			glue.Child(sprint.ServerRole,
@@ -157,10 +157,14 @@ func (t *application) PostConstruct() (err error) {
 			return err
 		}
 	}
+	t.detectProfile()
+	return nil
+}
+
+func (t *application) detectProfile() {
 	envName := strings.ToUpper(fmt.Sprintf("%s_%s", t.applicationName, "profile"))
 	t.applicationProfile = strings.ToLower(os.Getenv(envName))
 	t.devMode = t.applicationProfile == "dev"
-	return nil
 }
 
 func (t *application) AppendBeans(scan ...interface{}) {
@@ -236,6 +240,9 @@ func (t *application) Run(args []string) (err error) {
 
 	args = preprocessArgs(args)
 
+	// profile selects the overlay of properties before the context creation
+	t.detectProfile()
+
 	dep := &applicationDep{}
 	propertyFile := &glue.PropertySource{ Path: fmt.Sprintf("resources:%s.yml", t.applicationName) }
 	propertyMap := &glue.PropertySource{ Map: map[string]interface{} {
@@ -248,7 +255,18 @@ func (t *application) Run(args []string) (err error) {
 			"autoupdate": false,
 		},
 	}}
-	t.AppendBeans(dep, propertyFile, propertyMap, SystemEnvironmentPropertyResolver(t.applicationName, 10))
+	t.AppendBeans(dep, propertyFile, propertyMap, SystemEnvironmentPropertyResolver(t.applicationName, 10), FileSecretResolver(t.applicationName, FileSecretPriority))
+
+	if t.applicationProfile != "" {
+		profileFile := fmt.Sprintf("%s-%s.yml", t.applicationName, t.applicationProfile)
+		profileProperties, err := loadResourceProperties(t.beans, "resources", profileFile)
+		if err != nil {
+			return err
+		}
+		if profileProperties != nil {
+			t.AppendBeans(ProfilePropertyResolver(t.applicationProfile, profileFile, profileProperties, ProfilePropertyPriority))
+		}
+	}
 
 	ctx, err := glue.New(t.beans)
 	if err != nil {
@@ -136,6 +136,10 @@ func (t *implApplicationFlags) Node() int {
 	return 0
 }
 
+func (t *implApplicationFlags) SourceName() string {
+	return "flag"
+}
+
 func (t *implApplicationFlags) Priority() int {
 	return t.priority
 }
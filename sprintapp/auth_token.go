@@ -0,0 +1,250 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	JwtIssuerProperty   = "jwt.issuer"
+	JwtAudienceProperty = "jwt.audience"
+	JwtLeewayProperty   = "jwt.leeway"
+	JwtLegacyAcceptProperty = "jwt.legacy.accept"
+	JwtLegacyNever          = "never"  // value of 'jwt.legacy.accept' that rejects legacy tokens
+
+	JwtSecretKeyProperty = "jwt.secret.key"  // legacy key of tokens without 'kid' header
+	JwtKeyringPrefix     = "jwt.keyring."
+	JwtKeyringActive     = "jwt.keyring.active"
+	JwtKeyringKids       = "jwt.keyring.kids"
+
+	LegacyKid = "legacy"  // name of the legacy key in keygen commands
+
+	DefaultJwtLeeway = time.Minute
+)
+
+/**
+	Issuer and audience of JWT tokens default to the application name, so tokens of one application are not accepted by another one.
+ */
+
+func AuthTokenPolicy(applicationName string, properties glue.Properties) *sprintutils.AuthTokenPolicy {
+	policy := &sprintutils.AuthTokenPolicy{
+		Issuer:   properties.GetString(JwtIssuerProperty, applicationName),
+		Audience: properties.GetString(JwtAudienceProperty, applicationName),
+		Leeway:   int64(properties.GetDuration(JwtLeewayProperty, DefaultJwtLeeway) / time.Second),
+	}
+	switch value := properties.GetString(JwtLegacyAcceptProperty, ""); value {
+	case "":
+		// upgraded deployments keep working, see LegacyAuthTokensWarning
+		policy.LegacyUntil = sprintutils.LegacyNoDeadline
+	case JwtLegacyNever:
+	default:
+		// invalid deadline is rejected by the schema, it never enables legacy tokens
+		if deadline, err := ParseLegacyDeadline(value); err == nil {
+			policy.LegacyUntil = deadline.Unix()
+		}
+	}
+	return policy
+}
+
+/**
+	Returns the warning if legacy tokens signed by 'jwt.secret.key' are accepted without deadline, otherwise empty string.
+ */
+
+func LegacyAuthTokensWarning(properties glue.Properties) string {
+	if properties.GetString(JwtLegacyAcceptProperty, "") != "" || properties.GetString(JwtSecretKeyProperty, "") == "" {
+		return ""
+	}
+	return fmt.Sprintf("tokens without 'kid' header signed by '%s' are accepted without deadline, set '%s' to the date or '%s' to stop accepting them", JwtSecretKeyProperty, JwtLegacyAcceptProperty, JwtLegacyNever)
+}
+
+/**
+	Deadline of 'jwt.legacy.accept' is RFC3339 time or the date, 'never' and empty value are handled by AuthTokenPolicy.
+ */
+
+func ParseLegacyDeadline(value string) (time.Time, error) {
+	if value == "" || value == JwtLegacyNever {
+		return time.Time{}, nil
+	}
+	if deadline, err := time.Parse(time.RFC3339, value); err == nil {
+		return deadline, nil
+	}
+	deadline, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid deadline '%s', expected RFC3339 time or YYYY-MM-DD date", value)
+	}
+	return deadline, nil
+}
+
+/**
+	Keyring consists of the legacy 'jwt.secret.key' and keys listed in 'jwt.keyring.kids', each one is 'jwt.keyring.<kid>.key'.
+	Key is the base64 HMAC secret or PEM encoded RSA, ECDSA or Ed25519 key, public keys only verify tokens.
+	The active key 'jwt.keyring.active' signs new tokens, the legacy key signs them if the active key is not set.
+ */
+
+func JwtKeyProperty(kid string) string {
+	if kid == "" {
+		return JwtSecretKeyProperty
+	}
+	return fmt.Sprintf("%s%s.key", JwtKeyringPrefix, kid)
+}
+
+func LoadAuthKeyring(properties glue.Properties) (*sprintutils.AuthKeyring, error) {
+
+	keyring := &sprintutils.AuthKeyring{
+		Active: properties.GetString(JwtKeyringActive, ""),
+		Keys:   make(map[string]*sprintutils.AuthKey),
+	}
+
+	kids := append([]string{""}, ParseKids(properties.GetString(JwtKeyringKids, ""))...)
+	for _, kid := range kids {
+		value := properties.GetString(JwtKeyProperty(kid), "")
+		if value == "" {
+			continue
+		}
+		key, err := sprintutils.ParseAuthKey(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid jwt key '%s', %v", JwtKeyProperty(kid), err)
+		}
+		keyring.Keys[kid] = key
+	}
+
+	return keyring, nil
+}
+
+func ParseKids(list string) []string {
+	var kids []string
+	for _, kid := range strings.Split(list, ",") {
+		kid = strings.TrimSpace(kid)
+		if kid != "" {
+			kids = append(kids, kid)
+		}
+	}
+	return kids
+}
+
+/**
+	Serializes changes of the keyring, otherwise concurrent rotate and retire lose key IDs in the read-modify-write of 'jwt.keyring.kids'.
+	The lock guards only the current process, so keyring changes must run on one node at the time:
+	the running node through the control API, or the offline command when the node is stopped.
+	Nodes sharing the config store must not rotate or retire keys concurrently.
+ */
+
+var keyringMu sync.Mutex
+
+/**
+	Adds the new signing key of the algorithm to the keyring and makes it active, returns the key ID.
+	The key is stored before it is listed and activated, so nodes watching the config never sign with an unknown key.
+ */
+
+func RotateAuthKey(repo sprint.ConfigRepository, alg, username string) (string, error) {
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	kid, err := sprintutils.GenerateLongId()
+	if err != nil {
+		return "", err
+	}
+
+	value, err := sprintutils.GenerateAuthKey(alg)
+	if err != nil {
+		return "", err
+	}
+
+	list, err := repo.Get(JwtKeyringKids)
+	if err != nil {
+		return "", err
+	}
+
+	if err := setConfigAs(repo, JwtKeyProperty(kid), value, username); err != nil {
+		return "", err
+	}
+
+	kids := append(ParseKids(list), kid)
+	if err := setConfigAs(repo, JwtKeyringKids, strings.Join(kids, ","), username); err != nil {
+		return "", err
+	}
+
+	if err := setConfigAs(repo, JwtKeyringActive, kid, username); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+/**
+	Removes the verification key from the keyring, tokens signed by it are not accepted anymore.
+	The active key can not be retired, rotate it first.
+ */
+
+func RetireAuthKey(repo sprint.ConfigRepository, kid, username string) error {
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	if kid == LegacyKid {
+		kid = ""
+	}
+
+	active, err := repo.Get(JwtKeyringActive)
+	if err != nil {
+		return err
+	}
+
+	if kid == active {
+		name := kid
+		if name == "" {
+			name = LegacyKid
+		}
+		return errors.Errorf("jwt key '%s' is active, rotate it first", name)
+	}
+
+	if kid == "" {
+		return setConfigAs(repo, JwtSecretKeyProperty, "", username)
+	}
+
+	list, err := repo.Get(JwtKeyringKids)
+	if err != nil {
+		return err
+	}
+
+	var kids []string
+	found := false
+	for _, id := range ParseKids(list) {
+		if id == kid {
+			found = true
+		} else {
+			kids = append(kids, id)
+		}
+	}
+
+	if !found {
+		return errors.Errorf("jwt key '%s' not found in keyring", kid)
+	}
+
+	if err := setConfigAs(repo, JwtKeyringKids, strings.Join(kids, ","), username); err != nil {
+		return err
+	}
+	return setConfigAs(repo, JwtKeyProperty(kid), "", username)
+}
+
+/**
+	Sets the config entry on behalf of the user, so the history of the versioned repository records who changed the keyring.
+ */
+
+func setConfigAs(repo sprint.ConfigRepository, key, value, username string) error {
+	if versioned, ok := repo.(VersionedConfigRepository); ok {
+		return versioned.SetAs(key, value, username)
+	}
+	return repo.Set(key, value)
+}
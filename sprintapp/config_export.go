@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+
+	ConfigImportMerge   = "merge"    // sets entries from the file, keeps others
+	ConfigImportReplace = "replace"  // sets entries from the file, removes others
+)
+
+/**
+	Detects the format of the config file by extension, YAML is the default.
+ */
+
+func ConfigFormatOf(fileName string) string {
+	if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+		return ConfigFormatJSON
+	}
+	return ConfigFormatYAML
+}
+
+/**
+	Exports config entries with the prefix as the flat document of key-value pairs, hidden properties are skipped.
+	Sensitive values are masked by the policy, with nil policy they are revealed and secrets are decrypted.
+	Multi-line values like PEM are kept as is, YAML writes them as literal blocks.
+ */
+
+func ExportConfig(repo sprint.ConfigRepository, prefix, format string, mask *MaskPolicy) ([]byte, error) {
+
+	entries := make(map[string]string)
+	var secrets []string
+	err := repo.EnumerateAll(prefix, func(key, value string) bool {
+		if !IsHiddenProperty(key) {
+			if mask != nil {
+				entries[key] = mask.Mask(key, value)
+			} else {
+				entries[key] = value
+				if IsPasswordProperty(key) {
+					secrets = append(secrets, key)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// secrets are encrypted by the key of this config, export them decrypted
+	for _, key := range secrets {
+		if entries[key], err = repo.Get(key); err != nil {
+			return nil, errors.Errorf("decrypt config entry '%s', %v", key, err)
+		}
+	}
+
+	switch format {
+	case ConfigFormatJSON:
+		return json.MarshalIndent(entries, "", "  ")
+	case ConfigFormatYAML, "":
+		return yaml.Marshal(entries)
+	default:
+		return nil, errors.Errorf("unknown config format '%s'", format)
+	}
+}
+
+/**
+	Writes config entries with the prefix as 'key: value' lines, values longer than the limit are cut.
+	Sensitive values are masked by the policy, with nil policy they are revealed and secrets are decrypted.
+ */
+
+func DumpConfig(repo sprint.ConfigRepository, prefix string, limit int, mask *MaskPolicy, out io.StringWriter) error {
+
+	var keys []string
+	entries := make(map[string]string)
+	err := repo.EnumerateAll(prefix, func(key, value string) bool {
+		keys = append(keys, key)
+		entries[key] = value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		value := entries[key]
+		if mask != nil {
+			value = mask.Mask(key, value)
+		} else if IsPasswordProperty(key) {
+			if value, err = repo.Get(key); err != nil {
+				return errors.Errorf("decrypt config entry '%s', %v", key, err)
+			}
+		}
+		if len(value) > limit {
+			value = value[:limit] + "..."
+			value = strings.ReplaceAll(value, "\n", " ")
+		}
+		if _, err := out.WriteString(fmt.Sprintf("%s: %s\n", key, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+	Parses the document produced by ExportConfig. Nested maps are flattened to dot separated keys.
+ */
+
+func ParseConfig(data []byte, format string) (map[string]string, error) {
+
+	var doc map[string]interface{}
+	switch format {
+	case ConfigFormatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, errors.Errorf("parse json, %v", err)
+		}
+	case ConfigFormatYAML, "":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, errors.Errorf("parse yaml, %v", err)
+		}
+	default:
+		return nil, errors.Errorf("unknown config format '%s'", format)
+	}
+
+	entries := make(map[string]string)
+	flattenConfig("", doc, entries)
+	return entries, nil
+}
+
+func flattenConfig(prefix string, doc map[string]interface{}, entries map[string]string) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenConfig(key, val, entries)
+		case nil:
+			entries[key] = ""
+		case string:
+			entries[key] = val
+		default:
+			entries[key] = fmt.Sprint(val)
+		}
+	}
+}
+
+/**
+	Imports config entries on behalf of the user and returns the report of changes.
+	All entries are validated before the first change. In dry run mode nothing changes.
+	Masked values of the export keep current values.
+ */
+
+func ImportConfig(repo sprint.ConfigRepository, entries map[string]string, mode string, dryRun bool, username string) (string, error) {
+
+	if mode != ConfigImportMerge && mode != ConfigImportReplace {
+		return "", errors.Errorf("unknown import mode '%s'", mode)
+	}
+
+	current := make(map[string]string)
+	err := repo.EnumerateAll("", func(key, value string) bool {
+		current[key] = value
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	masked := make(map[string]bool)
+	for key, value := range entries {
+		if value == MaskedValue {
+			masked[key] = true
+		}
+	}
+
+	if resolver, ok := repo.(PropertySchemaResolver); ok {
+		for key, value := range entries {
+			if masked[key] {
+				continue
+			}
+			if def, ok := resolver.DescribeProperty(key); ok {
+				if err := def.Validate(value); err != nil {
+					return "", errors.Errorf("config entry '%s', %v", key, err)
+				}
+			}
+		}
+	}
+
+	changes := make(map[string]string)
+	var report []string
+
+	for key, value := range entries {
+		if masked[key] {
+			continue
+		}
+		old, exist := current[key]
+		if exist && IsPasswordProperty(key) {
+			// compare with the decrypted secret
+			if old, err = repo.Get(key); err != nil {
+				return "", errors.Errorf("decrypt config entry '%s', %v", key, err)
+			}
+		}
+		switch {
+		case !exist && value != "":
+			report = append(report, "+ " + key)
+		case exist && value == "":
+			report = append(report, "- " + key)
+		case exist && old != value:
+			report = append(report, "~ " + key)
+		default:
+			continue
+		}
+		changes[key] = value
+	}
+
+	if mode == ConfigImportReplace {
+		for key := range current {
+			if _, ok := entries[key]; !ok && !IsHiddenProperty(key) {
+				report = append(report, "- " + key)
+				changes[key] = ""
+			}
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i][2:] < report[j][2:]
+	})
+
+	if !dryRun {
+		versioned, isVersioned := repo.(VersionedConfigRepository)
+		for _, line := range report {
+			key := line[2:]
+			if isVersioned {
+				err = versioned.SetAs(key, changes[key], username)
+			} else {
+				err = repo.Set(key, changes[key])
+			}
+			if err != nil {
+				return "", errors.Errorf("set config entry '%s', %v", key, err)
+			}
+		}
+	}
+
+	var out strings.Builder
+	for _, line := range report {
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if dryRun {
+		out.WriteString(fmt.Sprintf("%d changes, dry run\n", len(report)))
+	} else {
+		out.WriteString(fmt.Sprintf("%d changes applied\n", len(report)))
+	}
+	return out.String(), nil
+}
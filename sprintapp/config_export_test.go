@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp_test
+
+import (
+	"context"
+	"github.com/keyvalstore/store"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type mapConfigRepository struct {
+	entries map[string]string
+}
+
+func (t *mapConfigRepository) Destroy() error {
+	return nil
+}
+
+func (t *mapConfigRepository) Priority() int {
+	return 100
+}
+
+func (t *mapConfigRepository) GetProperty(key string) (string, bool) {
+	value, ok := t.entries[key]
+	return value, ok
+}
+
+func (t *mapConfigRepository) Get(key string) (string, error) {
+	return t.entries[key], nil
+}
+
+func (t *mapConfigRepository) EnumerateAll(prefix string, cb func(key, value string) bool) error {
+	var keys []string
+	for key := range t.entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !cb(key, t.entries[key]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (t *mapConfigRepository) Set(key, value string) error {
+	if value == "" {
+		delete(t.entries, key)
+	} else {
+		t.entries[key] = value
+	}
+	return nil
+}
+
+func (t *mapConfigRepository) Watch(ctx context.Context, prefix string, cb func(key, value string) bool) (context.CancelFunc, error) {
+	return func() {}, nil
+}
+
+func (t *mapConfigRepository) Backend() store.DataStore {
+	return nil
+}
+
+func (t *mapConfigRepository) SetBackend(storage store.DataStore) {
+}
+
+func newMapConfig() *mapConfigRepository {
+	return &mapConfigRepository{entries: map[string]string{
+		"application.name":   "sprint",
+		"mail.smtp.password": "qwerty",
+		"tls.pem":            "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		".internal":          "hidden",
+	}}
+}
+
+func TestExportConfigMasked(t *testing.T) {
+
+	repo := newMapConfig()
+
+	data, err := sprintapp.ExportConfig(repo, "", sprintapp.ConfigFormatYAML, sprintapp.NewMaskPolicy("application.*"))
+	require.NoError(t, err)
+
+	entries, err := sprintapp.ParseConfig(data, sprintapp.ConfigFormatYAML)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"application.name":   sprintapp.MaskedValue,
+		"mail.smtp.password": sprintapp.MaskedValue,
+		"tls.pem":            sprintapp.MaskedValue,
+	}, entries)
+
+	// masked values keep current ones on import
+	report, err := sprintapp.ImportConfig(repo, entries, sprintapp.ConfigImportReplace, false, "admin")
+	require.NoError(t, err)
+	require.Equal(t, "0 changes applied\n", report)
+	require.Equal(t, "qwerty", repo.entries["mail.smtp.password"])
+}
+
+func TestExportImportConfig(t *testing.T) {
+
+	for _, format := range []string{sprintapp.ConfigFormatYAML, sprintapp.ConfigFormatJSON} {
+
+		data, err := sprintapp.ExportConfig(newMapConfig(), "", format, nil)
+		require.NoError(t, err)
+
+		entries, err := sprintapp.ParseConfig(data, format)
+		require.NoError(t, err)
+
+		target := &mapConfigRepository{entries: map[string]string{
+			"application.name": "old",
+			"obsolete.entry":   "value",
+			".internal":        "kept",
+		}}
+
+		report, err := sprintapp.ImportConfig(target, entries, sprintapp.ConfigImportReplace, true, "admin")
+		require.NoError(t, err)
+		require.Equal(t, "~ application.name\n+ mail.smtp.password\n- obsolete.entry\n+ tls.pem\n4 changes, dry run\n", report)
+		require.Equal(t, "old", target.entries["application.name"])
+
+		_, err = sprintapp.ImportConfig(target, entries, sprintapp.ConfigImportReplace, false, "admin")
+		require.NoError(t, err)
+
+		expected := newMapConfig().entries
+		expected[".internal"] = "kept"
+		require.Equal(t, expected, target.entries)
+	}
+}
+
+func TestParseConfigNested(t *testing.T) {
+
+	entries, err := sprintapp.ParseConfig([]byte("application:\n  name: sprint\n  port: 8080\njob:\n  backup:\n    singleton: true\n"), sprintapp.ConfigFormatYAML)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"application.name":     "sprint",
+		"application.port":     "8080",
+		"job.backup.singleton": "true",
+	}, entries)
+
+	_, err = sprintapp.ParseConfig([]byte("{}"), "xml")
+	require.Error(t, err)
+}
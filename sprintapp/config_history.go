@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/**
+	Versioned record of the single change of the config entry.
+ */
+
+type ConfigChange struct {
+	Version    int64    `json:"version"`
+	Timestamp  int64    `json:"timestamp"`  // unix millis
+	User       string   `json:"user"`
+	OldValue   string   `json:"old"`        // empty if entry was created
+	NewValue   string   `json:"new"`        // empty if entry was removed
+}
+
+var VersionedConfigRepositoryClass = reflect.TypeOf((*VersionedConfigRepository)(nil)).Elem()
+
+/**
+	Optional extension of sprint.ConfigRepository that keeps the history of changes.
+ */
+
+type VersionedConfigRepository interface {
+
+	/**
+	Sets the config entry on behalf of the user and records the change in history.
+	 */
+
+	SetAs(key, value, username string) error
+
+	/**
+	Gets all changes of the config entry, the oldest first.
+	 */
+
+	History(key string) ([]*ConfigChange, error)
+
+	/**
+	Sets the config entry to the value it had after the change with the version.
+	Rollback is recorded in history as the new change.
+	 */
+
+	Rollback(key string, version int64, username string) error
+
+}
+
+/**
+	Formats the history of the config entry for the console output, hides values of sensitive properties.
+ */
+
+func FormatConfigHistory(key string, history []*ConfigChange, mask *MaskPolicy) string {
+	var out strings.Builder
+	for _, change := range history {
+		oldValue, newValue := mask.Mask(key, change.OldValue), mask.Mask(key, change.NewValue)
+		out.WriteString(fmt.Sprintf("%d, %s, user '%s', '%s' -> '%s'\n", change.Version, time.UnixMilli(change.Timestamp).Format(time.RFC3339), change.User, oldValue, newValue))
+	}
+	return out.String()
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"github.com/codeallergy/glue"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	MaskedValue = "******"
+
+	/**
+	Comma separated list of glob patterns of additional sensitive config keys, for example 'mailgun.*,*.api-key'.
+	 */
+
+	MaskPatternsProperty = "application.mask.patterns"
+
+	RevealFlag = "--reveal"
+)
+
+/**
+	Single policy of masking sensitive config values, used by the control API, offline commands and logs.
+	Hidden, password and PEM properties are always sensitive, patterns add application specific ones.
+ */
+
+type MaskPolicy struct {
+	Patterns []string
+}
+
+func NewMaskPolicy(patterns string) *MaskPolicy {
+	t := new(MaskPolicy)
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			t.Patterns = append(t.Patterns, pattern)
+		}
+	}
+	return t
+}
+
+/**
+	Gets the policy with the patterns from properties, call it on each use to follow changes of the config.
+ */
+
+func ConfigMaskPolicy(properties glue.Properties) *MaskPolicy {
+	if properties == nil {
+		return NewMaskPolicy("")
+	}
+	return NewMaskPolicy(properties.GetString(MaskPatternsProperty, ""))
+}
+
+func (t *MaskPolicy) IsSensitive(key string) bool {
+	if IsHiddenProperty(key) || IsPasswordProperty(key) || IsPEMProperty(key) {
+		return true
+	}
+	for _, pattern := range t.Patterns {
+		if matched, _ := filepath.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *MaskPolicy) Mask(key, value string) string {
+	if value != "" && t.IsSensitive(key) {
+		return MaskedValue
+	}
+	return value
+}
+
+/**
+	Removes the sensitive value from the error message, parsing errors usually quote the value.
+ */
+
+func (t *MaskPolicy) MaskError(key, value string, err error) error {
+	if err == nil || value == "" || !t.IsSensitive(key) {
+		return err
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, value) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(msg, value, MaskedValue))
+}
+
+/**
+	Removes reveal flag from the command arguments and returns true if it was there.
+ */
+
+func ExtractRevealFlag(args []string) ([]string, bool) {
+	var rest []string
+	reveal := false
+	for _, arg := range args {
+		if arg == RevealFlag {
+			reveal = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return rest, reveal
+}
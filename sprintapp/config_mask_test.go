@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp_test
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMaskPolicy(t *testing.T) {
+
+	mask := sprintapp.NewMaskPolicy(" mailgun.* , ,*.api-key")
+	require.Equal(t, []string{"mailgun.*", "*.api-key"}, mask.Patterns)
+
+	for _, key := range []string{".internal", "mail.smtp.password", "jwt.secret.key", "tls.pem", "mailgun.domain", "payment.api-key"} {
+		require.True(t, mask.IsSensitive(key), key)
+		require.Equal(t, sprintapp.MaskedValue, mask.Mask(key, "value"), key)
+	}
+
+	for _, key := range []string{"application.name", "mailgun", "payment.api-key.enabled"} {
+		require.False(t, mask.IsSensitive(key), key)
+		require.Equal(t, "value", mask.Mask(key, "value"), key)
+	}
+
+	// empty value shows that the entry is not set
+	require.Equal(t, "", mask.Mask("mail.smtp.password", ""))
+
+	require.Empty(t, sprintapp.ConfigMaskPolicy(nil).Patterns)
+}
+
+func TestMaskError(t *testing.T) {
+
+	mask := sprintapp.NewMaskPolicy("")
+
+	err := mask.MaskError("mail.smtp.password", "qwerty", errors.New("invalid value 'qwerty'"))
+	require.Equal(t, "invalid value '******'", err.Error())
+
+	plain := errors.New("invalid value 'sprint'")
+	require.Equal(t, plain, mask.MaskError("application.name", "sprint", plain))
+	require.Equal(t, plain, mask.MaskError("mail.smtp.password", "qwerty", plain))
+	require.Nil(t, mask.MaskError("mail.smtp.password", "qwerty", nil))
+}
+
+func TestExtractRevealFlag(t *testing.T) {
+
+	args, reveal := sprintapp.ExtractRevealFlag([]string{"mail", sprintapp.RevealFlag, "10"})
+	require.True(t, reveal)
+	require.Equal(t, []string{"mail", "10"}, args)
+
+	args, reveal = sprintapp.ExtractRevealFlag([]string{"mail"})
+	require.False(t, reveal)
+	require.Equal(t, []string{"mail"}, args)
+}
+
+func TestDumpConfigMasked(t *testing.T) {
+
+	var out strings.Builder
+	require.NoError(t, sprintapp.DumpConfig(newMapConfig(), "", 80, sprintapp.NewMaskPolicy(""), &out))
+	require.Equal(t, ".internal: ******\napplication.name: sprint\nmail.smtp.password: ******\ntls.pem: ******\n", out.String())
+
+	// reveal
+	out.Reset()
+	require.NoError(t, sprintapp.DumpConfig(newMapConfig(), "mail.", 80, nil, &out))
+	require.Equal(t, "mail.smtp.password: qwerty\n", out.String())
+
+	out.Reset()
+	require.NoError(t, sprintapp.DumpConfig(newMapConfig(), "tls.", 10, nil, &out))
+	require.Equal(t, "tls.pem: -----BEGIN...\n", out.String())
+}
+
+func TestFormatConfigHistoryMasked(t *testing.T) {
+
+	history := []*sprintapp.ConfigChange{
+		{Version: 2, Timestamp: 1000, User: "admin", OldValue: "old", NewValue: "new"},
+	}
+
+	content := sprintapp.FormatConfigHistory("mail.smtp.password", history, sprintapp.NewMaskPolicy(""))
+	require.True(t, strings.HasSuffix(content, "user 'admin', '******' -> '******'\n"), content)
+
+	content = sprintapp.FormatConfigHistory("application.name", history, sprintapp.NewMaskPolicy(""))
+	require.True(t, strings.HasSuffix(content, "user 'admin', 'old' -> 'new'\n"), content)
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PropertyType string
+
+const (
+	StringProperty   PropertyType = "string"
+	DurationProperty PropertyType = "duration"
+	IntProperty      PropertyType = "int"
+	BoolProperty     PropertyType = "bool"
+	AddressProperty  PropertyType = "address"
+	FileModeProperty PropertyType = "filemode"
+	PEMProperty      PropertyType = "pem"
+)
+
+/**
+	Documentation and validation rules of the config entry.
+	Key could be the pattern in filepath.Match syntax, for example '*.bind-address'.
+ */
+
+type PropertyDef struct {
+	Key          string
+	Type         PropertyType
+	Default      string
+	Description  string
+	Validator    func(value string) error   // optional, called after the type check
+}
+
+var PropertySchemaClass = reflect.TypeOf((*PropertySchema)(nil)).Elem()
+
+/**
+	Components register schema of their config entries as beans implementing this interface.
+ */
+
+type PropertySchema interface {
+
+	PropertyDefs() []*PropertyDef
+
+}
+
+var PropertySchemaResolverClass = reflect.TypeOf((*PropertySchemaResolver)(nil)).Elem()
+
+/**
+	Optional extension of sprint.ConfigRepository that knows registered schemas.
+ */
+
+type PropertySchemaResolver interface {
+
+	/**
+	Finds the definition of the config entry, exact keys have precedence over patterns.
+	 */
+
+	DescribeProperty(key string) (*PropertyDef, bool)
+
+}
+
+type propertySchema struct {
+	defs []*PropertyDef
+}
+
+func NewPropertySchema(defs ...*PropertyDef) PropertySchema {
+	return &propertySchema{defs: defs}
+}
+
+func (t *propertySchema) PropertyDefs() []*PropertyDef {
+	return t.defs
+}
+
+/**
+	Schema of the config entries used by the framework itself.
+ */
+
+func DefaultPropertySchema() PropertySchema {
+	return NewPropertySchema(
+		&PropertyDef{Key: "*.bind-address", Type: AddressProperty, Description: "Listen address of the server in the form 'host:port'."},
+		&PropertyDef{Key: "*.connect-address", Type: AddressProperty, Description: "Address of the server to connect by the client in the form 'host:port'."},
+		&PropertyDef{Key: "*-server.read-timeout", Type: DurationProperty, Default: "30s", Description: "Maximum duration for reading the entire HTTP/1 request, applied to the next requests without restart, the limit of reading headers needs restart."},
+		&PropertyDef{Key: "*-server.write-timeout", Type: DurationProperty, Default: "30s", Description: "Maximum duration before timing out writes of the HTTP/1 response, applied to the next requests without restart."},
+		&PropertyDef{Key: "*-server.idle-timeout", Type: DurationProperty, Default: "1m", Description: "Maximum amount of time to wait for the next HTTP request on keep-alive connection, requires restart."},
+		&PropertyDef{Key: "*.insecure", Type: BoolProperty, Default: "false", Description: "Disables TLS of the server or client."},
+		&PropertyDef{Key: "*.max-message-size", Type: IntProperty, Default: "0", Description: "Maximum size of the gRPC message in bytes, zero means default."},
+		&PropertyDef{Key: "redirect-https.redirect-address", Type: AddressProperty, Description: "Address to redirect HTTP requests to."},
+		&PropertyDef{Key: "application.perm.*", Type: FileModeProperty, Description: "Permissions of the application files and directories in the form '-rwxrwxr-x'."},
+		&PropertyDef{Key: "config.watch.queue-size", Type: IntProperty, Default: "64", Description: "Maximum number of pending changes per config watcher.", Validator: positiveInt},
+		&PropertyDef{Key: "config.watch.overflow", Type: StringProperty, Default: "coalesce", Description: "Policy for the full queue of the config watcher: drop-oldest, coalesce or disconnect.", Validator: oneOf("drop-oldest", "coalesce", "disconnect")},
+		&PropertyDef{Key: "application.log.level", Type: StringProperty, Default: "debug", Description: "Minimum level of the log messages, applied without restart.", Validator: oneOf("debug", "info", "warn", "error", "dpanic", "panic", "fatal")},
+		&PropertyDef{Key: "application.autoupdate", Type: BoolProperty, Default: "false", Description: "Enables automatic updates of the application."},
+		&PropertyDef{Key: "application.mask.patterns", Type: StringProperty, Description: "Comma separated glob patterns of config keys with sensitive values, masked in addition to hidden, password and PEM keys."},
+		&PropertyDef{Key: "lumberjack.max-size", Type: IntProperty, Default: "500", Description: "Maximum size of the log file in megabytes before it gets rotated, applied without restart."},
+		&PropertyDef{Key: "lumberjack.max-backups", Type: IntProperty, Default: "10", Description: "Maximum number of old log files to retain."},
+		&PropertyDef{Key: "lumberjack.max-age", Type: IntProperty, Default: "28", Description: "Maximum number of days to retain old log files."},
+		&PropertyDef{Key: "lumberjack.compress", Type: BoolProperty, Default: "false", Description: "Compresses rotated log files with gzip."},
+		&PropertyDef{Key: "lumberjack.rotate-on-start", Type: BoolProperty, Default: "false", Description: "Rotates the log file on application start."},
+		&PropertyDef{Key: "job.max-concurrency", Type: IntProperty, Default: "4", Description: "Maximum number of jobs running at the same time, zero for unlimited.", Validator: nonNegativeInt},
+		&PropertyDef{Key: "job.history.max-records", Type: IntProperty, Default: "100", Description: "Maximum number of execution records kept per job, the oldest ones are removed when the job finishes."},
+		&PropertyDef{Key: "job.history.retention", Type: DurationProperty, Default: "0s", Description: "Age of the finished execution records removed when the job finishes, zero keeps them until 'job.history.max-records'."},
+		&PropertyDef{Key: "job.*.concurrency", Type: StringProperty, Default: "allow", Description: "Policy for overlapping runs of the job: allow, skip or queue.", Validator: oneOf("allow", "skip", "queue")},
+		&PropertyDef{Key: "job.*.singleton", Type: BoolProperty, Default: "false", Description: "Runs the job on a single node of the cluster at a time."},
+		&PropertyDef{Key: "job.*.lease-ttl", Type: DurationProperty, Default: "30s", Description: "Lease time of the singleton job lock."},
+		&PropertyDef{Key: "job.*.retry.max-attempts", Type: IntProperty, Default: "1", Description: "Maximum number of attempts to run the failed job.", Validator: positiveInt},
+		&PropertyDef{Key: "job.*.retry.initial-backoff", Type: DurationProperty, Default: "1s", Description: "Delay before the first retry of the failed job."},
+		&PropertyDef{Key: "job.*.retry.max-backoff", Type: DurationProperty, Default: "1m", Description: "Maximum delay between retries of the failed job."},
+		&PropertyDef{Key: "*.rate-limit", Type: DurationProperty, Default: "100ms", Description: "Minimum interval between requests of the rate limiter, applied without restart."},
+		&PropertyDef{Key: "jwt.secret.key", Type: StringProperty, Description: "Secret key to sign and verify JWT tokens."},
+		&PropertyDef{Key: "jwt.keyring.active", Type: StringProperty, Description: "Key ID of the JWT signing key, the legacy 'jwt.secret.key' signs tokens if not set."},
+		&PropertyDef{Key: "jwt.keyring.kids", Type: StringProperty, Description: "Comma separated key IDs of the JWT keyring, managed by 'keygen rotate-jwt' and 'keygen retire-jwt' commands."},
+		&PropertyDef{Key: "jwt.keyring.*.key", Type: StringProperty, Description: "Key of the JWT keyring with the key ID, base64 HMAC secret or PEM encoded RSA, ECDSA or Ed25519 key."},
+		&PropertyDef{Key: "jwt.issuer", Type: StringProperty, Description: "Issuer claim of JWT tokens, the application name by default."},
+		&PropertyDef{Key: "jwt.audience", Type: StringProperty, Description: "Audience claim of JWT tokens, the application name by default."},
+		&PropertyDef{Key: "jwt.legacy.accept", Type: StringProperty, Description: "Deadline as RFC3339 time or YYYY-MM-DD date, until then tokens issued before JWT IDs without 'kid' are accepted with 'jwt.secret.key', 'never' rejects them, empty accepts them without deadline and logs a warning.", Validator: legacyDeadline},
+		&PropertyDef{Key: "jwt.revocation.gc-interval", Type: DurationProperty, Default: "1h", Description: "Interval of removing expired token revocations from the store, zero disables it."},
+		&PropertyDef{Key: "jwt.revocation.refresh-interval", Type: DurationProperty, Default: "10s", Description: "Interval of loading token revocations of other nodes from the store, zero disables it."},
+		&PropertyDef{Key: "jwt.leeway", Type: DurationProperty, Default: "1m", Description: "Allowed clock skew between nodes on verification of JWT token time claims."},
+		&PropertyDef{Key: "*.pem", Type: PEMProperty, Description: "PEM encoded certificate or key."},
+	)
+}
+
+/**
+	Finds the definition of the config entry in schemas, exact keys have precedence over patterns.
+ */
+
+func FindPropertyDef(schemas []PropertySchema, key string) (*PropertyDef, bool) {
+	var found *PropertyDef
+	for _, schema := range schemas {
+		for _, def := range schema.PropertyDefs() {
+			if def.Key == key {
+				return def, true
+			}
+			if found == nil {
+				if matched, _ := filepath.Match(def.Key, key); matched {
+					found = def
+				}
+			}
+		}
+	}
+	return found, found != nil
+}
+
+/**
+	Validates the value of the config entry. Empty value is always valid, because it removes the entry.
+ */
+
+func (t *PropertyDef) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if err := validatePropertyType(t.Type, value); err != nil {
+		return errors.Errorf("invalid %s value, %v", t.Type, err)
+	}
+	if t.Validator != nil {
+		return t.Validator(value)
+	}
+	return nil
+}
+
+func (t *PropertyDef) String() string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Key:         %s\n", t.Key))
+	out.WriteString(fmt.Sprintf("Type:        %s\n", t.Type))
+	if t.Default != "" {
+		out.WriteString(fmt.Sprintf("Default:     %s\n", t.Default))
+	}
+	if t.Description != "" {
+		out.WriteString(fmt.Sprintf("Description: %s\n", t.Description))
+	}
+	return out.String()
+}
+
+func validatePropertyType(typ PropertyType, value string) error {
+	switch typ {
+	case DurationProperty:
+		_, err := time.ParseDuration(value)
+		return err
+	case IntProperty:
+		_, err := strconv.Atoi(value)
+		return err
+	case BoolProperty:
+		_, err := strconv.ParseBool(value)
+		return err
+	case AddressProperty:
+		_, port, err := net.SplitHostPort(value)
+		if err != nil {
+			return err
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+			return errors.Errorf("invalid port '%s'", port)
+		}
+		return nil
+	case FileModeProperty:
+		return validateFileMode(value)
+	case PEMProperty:
+		if block, _ := pem.Decode([]byte(value)); block == nil {
+			return errors.New("no PEM block found")
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+func validateFileMode(value string) error {
+	const rwx = "rwxrwxrwx"
+	if len(value) != len(rwx) + 1 {
+		return errors.Errorf("expected the form '-rwxrwxrwx', but found '%s'", value)
+	}
+	for i, c := range value[1:] {
+		if c != '-' && byte(c) != rwx[i] {
+			return errors.Errorf("unexpected symbol '%c' in '%s'", c, value)
+		}
+	}
+	return nil
+}
+
+func positiveInt(value string) error {
+	if v, _ := strconv.Atoi(value); v <= 0 {
+		return errors.Errorf("expected positive number, but found '%s'", value)
+	}
+	return nil
+}
+
+func nonNegativeInt(value string) error {
+	if v, _ := strconv.Atoi(value); v < 0 {
+		return errors.Errorf("expected zero or positive number, but found '%s'", value)
+	}
+	return nil
+}
+
+func legacyDeadline(value string) error {
+	_, err := ParseLegacyDeadline(value)
+	return err
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if v == value {
+				return nil
+			}
+		}
+		return errors.Errorf("expected one of %v, but found '%s'", values, value)
+	}
+}
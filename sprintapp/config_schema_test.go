@@ -0,0 +1,23 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestJobMaxConcurrencySchema(t *testing.T) {
+
+	def, ok := sprintapp.FindPropertyDef([]sprintapp.PropertySchema{sprintapp.DefaultPropertySchema()}, "job.max-concurrency")
+	require.True(t, ok)
+
+	// zero is unlimited
+	require.NoError(t, def.Validate("0"))
+	require.NoError(t, def.Validate("4"))
+	require.Error(t, def.Validate("-1"))
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"reflect"
+)
+
+var SecretConfigRepositoryClass = reflect.TypeOf((*SecretConfigRepository)(nil)).Elem()
+
+/**
+	Optional extension of sprint.ConfigRepository that encrypts values of password properties.
+ */
+
+type SecretConfigRepository interface {
+
+	/**
+	Re-encrypts all secrets for the new 'application.boot' token, returns the number of secrets.
+	 */
+
+	RotateSecretKey(newBootToken string) (int, error)
+
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/**
+	Container secrets are mounted as files, where the file name is the property key, for example '/run/secrets/application.boot'.
+	The directory is set by <NAME>_SECRETS_DIR environment variable.
+ */
+
+var (
+	DefaultSecretsDir = "/run/secrets"
+
+	FileSecretPriority = 100  // between env and store
+
+	MaxSecretFileSize = int64(64 * 1024)
+)
+
+var PropertyChangeSourceClass = reflect.TypeOf((*PropertyChangeSource)(nil)).Elem()
+
+/**
+	Property resolver that changes values at runtime, config repository delivers those changes to its watchers.
+ */
+
+type PropertyChangeSource interface {
+
+	/**
+	Subscribes on changes of properties, value is empty if the property was removed.
+	 */
+
+	SubscribeChanges(cb func(key, value string)) (cancel func(), err error)
+
+}
+
+type implFileSecretResolver struct {
+	dir      string
+	priority int
+
+	mu       sync.RWMutex
+	values   map[string]string
+
+	muWatch   sync.Mutex
+	watcher   *fsnotify.Watcher
+	nextId    int64
+	subs      map[int64]func(key, value string)
+}
+
+func FileSecretResolver(applicationName string, priority int) glue.PropertyResolver {
+	dir := os.Getenv(SecretsDirVariable(applicationName))
+	if dir == "" {
+		dir = DefaultSecretsDir
+	}
+	t := &implFileSecretResolver{
+		dir: dir,
+		priority: priority,
+		subs: make(map[int64]func(key, value string)),
+	}
+	// values are needed before the first injection of properties
+	t.values = t.readAll()
+	return t
+}
+
+func SecretsDirVariable(applicationName string) string {
+	return strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, "secrets_dir"))
+}
+
+func (t *implFileSecretResolver) String() string {
+	return fmt.Sprintf("FileSecretResolver{%s,%d}", t.dir, t.priority)
+}
+
+func (t *implFileSecretResolver) SourceName() string {
+	return "secret"
+}
+
+func (t *implFileSecretResolver) Priority() int {
+	return t.priority
+}
+
+func (t *implFileSecretResolver) GetProperty(key string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	value, ok := t.values[key]
+	return value, ok && value != ""
+}
+
+/**
+	Reads all regular files of the directory, hidden files like '..data' of kubernetes are skipped.
+ */
+
+func (t *implFileSecretResolver) readAll() map[string]string {
+	values := make(map[string]string)
+	list, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		return values
+	}
+	for _, fi := range list {
+		name := fi.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		// kubernetes mounts secrets as symlinks
+		path := filepath.Join(t.dir, name)
+		stat, err := os.Stat(path)
+		if err != nil || !stat.Mode().IsRegular() || stat.Size() > MaxSecretFileSize {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		values[name] = strings.TrimRight(string(content), "\r\n")
+	}
+	return values
+}
+
+func (t *implFileSecretResolver) SubscribeChanges(cb func(key, value string)) (cancel func(), err error) {
+	t.muWatch.Lock()
+	defer t.muWatch.Unlock()
+
+	if t.watcher == nil {
+		if _, err := os.Stat(t.dir); err != nil {
+			return nil, errors.Errorf("secrets directory '%s' not found, %v", t.dir, err)
+		}
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, errors.Errorf("create watcher %v", err)
+		}
+		if err := watcher.Add(t.dir); err != nil {
+			watcher.Close()
+			return nil, errors.Errorf("listen updates on directory '%s' by watcher %v", t.dir, err)
+		}
+		t.watcher = watcher
+		go t.watchLoop(watcher)
+	}
+
+	t.nextId++
+	id := t.nextId
+	t.subs[id] = cb
+
+	return func() {
+		t.muWatch.Lock()
+		defer t.muWatch.Unlock()
+		delete(t.subs, id)
+	}, nil
+}
+
+func (t *implFileSecretResolver) watchLoop(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			t.refresh()
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+/**
+	Re-reads the directory on any event, because kubernetes replaces all files at once by swapping '..data' symlink.
+ */
+
+func (t *implFileSecretResolver) refresh() {
+	values := t.readAll()
+
+	t.mu.Lock()
+	old := t.values
+	t.values = values
+	t.mu.Unlock()
+
+	changes := make(map[string]string)
+	for key, value := range values {
+		if prev, ok := old[key]; !ok || prev != value {
+			changes[key] = value
+		}
+	}
+	for key := range old {
+		if _, ok := values[key]; !ok {
+			changes[key] = ""
+		}
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	t.muWatch.Lock()
+	subs := make([]func(key, value string), 0, len(t.subs))
+	for _, cb := range t.subs {
+		subs = append(subs, cb)
+	}
+	t.muWatch.Unlock()
+
+	for key, value := range changes {
+		for _, cb := range subs {
+			cb(key, value)
+		}
+	}
+}
+
+func (t *implFileSecretResolver) Destroy() error {
+	t.muWatch.Lock()
+	defer t.muWatch.Unlock()
+	if t.watcher != nil {
+		t.watcher.Close()
+		t.watcher = nil
+	}
+	return nil
+}
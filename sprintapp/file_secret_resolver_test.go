@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type secretChange struct {
+	key, value string
+}
+
+func TestFileSecretResolver(t *testing.T) {
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "application.boot"), []byte("boot\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..data"), []byte("hidden"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mail.smtp.password"), []byte(""), 0600))
+	t.Setenv(sprintapp.SecretsDirVariable("test"), dir)
+
+	resolver := sprintapp.FileSecretResolver("test", sprintapp.FileSecretPriority)
+	require.Equal(t, sprintapp.FileSecretPriority, resolver.Priority())
+
+	value, ok := resolver.GetProperty("application.boot")
+	require.True(t, ok)
+	require.Equal(t, "boot", value)
+
+	_, ok = resolver.GetProperty("..data")
+	require.False(t, ok)
+
+	// empty file does not override lower sources
+	_, ok = resolver.GetProperty("mail.smtp.password")
+	require.False(t, ok)
+}
+
+func TestFileSecretResolverChanges(t *testing.T) {
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "application.boot"), []byte("boot"), 0600))
+	t.Setenv(sprintapp.SecretsDirVariable("test"), dir)
+
+	resolver := sprintapp.FileSecretResolver("test", sprintapp.FileSecretPriority)
+	defer resolver.(interface{ Destroy() error }).Destroy()
+
+	source, ok := resolver.(sprintapp.PropertyChangeSource)
+	require.True(t, ok)
+
+	ch := make(chan secretChange, 16)
+	cancel, err := source.SubscribeChanges(func(key, value string) {
+		ch <- secretChange{key, value}
+	})
+	require.NoError(t, err)
+	defer cancel()
+
+	receive := func() secretChange {
+		select {
+		case c := <-ch:
+			return c
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "secret change was not delivered")
+			return secretChange{}
+		}
+	}
+
+	// the file appears at once like the kubernetes update does
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".application.auth"), []byte("auth"), 0600))
+	require.NoError(t, os.Rename(filepath.Join(dir, ".application.auth"), filepath.Join(dir, "application.auth")))
+	require.Equal(t, secretChange{"application.auth", "auth"}, receive())
+
+	value, ok := resolver.GetProperty("application.auth")
+	require.True(t, ok)
+	require.Equal(t, "auth", value)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "application.boot")))
+	require.Equal(t, secretChange{"application.boot", ""}, receive())
+
+	_, ok = resolver.GetProperty("application.boot")
+	require.False(t, ok)
+}
+
+func TestFileSecretResolverNoDir(t *testing.T) {
+
+	t.Setenv(sprintapp.SecretsDirVariable("test"), filepath.Join(t.TempDir(), "missing"))
+
+	resolver := sprintapp.FileSecretResolver("test", sprintapp.FileSecretPriority)
+	_, ok := resolver.GetProperty("application.boot")
+	require.False(t, ok)
+
+	_, err := resolver.(sprintapp.PropertyChangeSource).SubscribeChanges(func(key, value string) {})
+	require.Error(t, err)
+}
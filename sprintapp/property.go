@@ -8,30 +8,65 @@ package sprintapp
 import (
 	"fmt"
 	"github.com/codeallergy/glue"
+	"github.com/pkg/errors"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 )
 
+/**
+	By default only 'application.*' properties are mapped to environment variables, for example 'application.boot' to SPRINT_BOOT.
+	Set <NAME>_ENV_MAPPING=all to map every property, for example 'control-grpc-server.bind-address' to SPRINT_CONTROL_GRPC_SERVER_BIND_ADDRESS.
+ */
+
+var (
+	EnvMappingApplication = "application"
+	EnvMappingAll         = "all"
+)
+
+/**
+	Optional interface of the environment property resolver that finds variables not mapped to any property.
+ */
+
+type EnvironmentInspector interface {
+
+	UnknownEnviron(keys []string) []string
+
+}
+
 type systemEnvironmentPropertyResolver struct {
 	applicationName string
 	priority int
+	mapping  string
 
 	sync.Mutex
 	cache map[string]string
+	envKeys map[string]string  // env, the property key that owns the env
 }
 
 func SystemEnvironmentPropertyResolver(applicationName string, priority int) glue.PropertyResolver {
+	mapping := strings.ToLower(os.Getenv(EnvMappingVariable(applicationName)))
+	if mapping != EnvMappingAll {
+		mapping = EnvMappingApplication
+	}
 	return &systemEnvironmentPropertyResolver{
 		applicationName: applicationName,
 		priority: priority,
+		mapping: mapping,
 		cache: make(map[string]string),
+		envKeys: make(map[string]string),
 	}
 }
 
 func (t *systemEnvironmentPropertyResolver) String() string {
-	return fmt.Sprintf("SystemEnvironmentPropertyResolver{%s,%d}", t.applicationName, t.priority)
+	return fmt.Sprintf("SystemEnvironmentPropertyResolver{%s,%s,%d}", t.applicationName, t.mapping, t.priority)
+}
+
+func (t *systemEnvironmentPropertyResolver) SourceName() string {
+	return "env"
 }
 
 func (t *systemEnvironmentPropertyResolver) Priority() int {
@@ -59,14 +94,93 @@ func (t *systemEnvironmentPropertyResolver) GetProperty(key string) (string, boo
 	return "", false
 }
 
+/**
+	The first property that maps to the environment variable owns it, other properties with the same name are not mapped.
+ */
+
 func (t *systemEnvironmentPropertyResolver) toEnv(key string) (string, bool) {
+	if !IsEnvMapped(t.mapping, key) {
+		return "", false
+	}
+	env := PropertyEnvName(t.applicationName, key)
+
+	t.Lock()
+	defer t.Unlock()
+	if owner, ok := t.envKeys[env]; ok && owner != key {
+		return "", false
+	}
+	t.envKeys[env] = key
+	return env, true
+}
+
+/**
+	Name of the environment variable that enables the mapping of all properties.
+ */
+
+func EnvMappingVariable(applicationName string) string {
+	return strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, "env_mapping"))
+}
+
+func IsEnvMapped(mapping, key string) bool {
+	return mapping == EnvMappingAll || strings.HasPrefix(key, "application.")
+}
+
+/**
+	Gets the name of the environment variable for the property.
+	Names of 'application.*' properties are the same as before the full mapping, the prefix is omitted and dots are replaced by underscore.
+	Other properties have dots, dashes and characters not allowed in the variable name replaced by underscore, '*' of schema patterns is kept.
+ */
+
+func PropertyEnvName(applicationName, key string) string {
 	if strings.HasPrefix(key, "application.") {
 		prop := strings.ReplaceAll(key[len("application."):], ".", "_")
-		env := strings.ToUpper(fmt.Sprintf("%s_%s", t.applicationName, prop))
-		return env, true
-	} else {
-		return "", false
+		return strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, prop))
+	}
+	escaped := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '*':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, escaped))
+}
+
+/**
+	Variables with the application prefix that are used by the framework itself and are not properties.
+ */
+
+func ReservedEnvNames(applicationName string) []string {
+	return []string{
+		EnvMappingVariable(applicationName),
+		SecretsDirVariable(applicationName),
+		strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, "profile")),
+		strings.ToUpper(fmt.Sprintf("%s_%s", applicationName, "auth")),
+	}
+}
+
+/**
+	Maps properties to names of environment variables, the first property owns the name in the same way as in the resolver.
+	Returns the mapping without colliding properties and the error about the first collision.
+ */
+
+func PropertyEnvMapping(applicationName string, keys []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	owners := make(map[string]string)
+	var collision error
+	for _, key := range keys {
+		env := PropertyEnvName(applicationName, key)
+		if owner, ok := owners[env]; ok && owner != key {
+			if collision == nil {
+				collision = errors.Errorf("properties '%s' and '%s' map to the same environment variable %s", owner, key, env)
+			}
+			continue
+		}
+		owners[env] = key
+		mapping[key] = env
 	}
+	return mapping, collision
 }
 
 func (t *systemEnvironmentPropertyResolver) PromptProperty(key string) (string, bool) {
@@ -87,6 +201,62 @@ func (t *systemEnvironmentPropertyResolver) PromptProperty(key string) (string,
 	return "", false
 }
 
+/**
+	Lists variables with the application prefix that map to none of the given or already requested properties, they are likely misspelled.
+	Keys could be schema patterns with '*', properties that are not mapped by the current mapping are ignored.
+ */
+
+func (t *systemEnvironmentPropertyResolver) UnknownEnviron(keys []string) []string {
+
+	known := make(map[string]bool)
+	for _, env := range ReservedEnvNames(t.applicationName) {
+		known[env] = true
+	}
+
+	var patterns []string
+	for _, key := range keys {
+		if !IsEnvMapped(t.mapping, key) {
+			continue
+		}
+		env := PropertyEnvName(t.applicationName, key)
+		if strings.Contains(env, "*") {
+			patterns = append(patterns, env)
+		} else {
+			known[env] = true
+		}
+	}
+
+	t.Lock()
+	for env := range t.envKeys {
+		known[env] = true
+	}
+	t.Unlock()
+
+	prefix := strings.ToUpper(t.applicationName) + "_"
+	var list []string
+	for _, entry := range os.Environ() {
+		env := entry
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			env = entry[:i]
+		}
+		if strings.HasPrefix(env, prefix) && !known[env] && !matchAny(patterns, env) {
+			list = append(list, env)
+		}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *systemEnvironmentPropertyResolver) Environ(withValues bool) []string {
 	var list []string
 	t.Lock()
@@ -105,8 +275,12 @@ func IsPEMProperty(key string) bool {
 	return strings.HasSuffix(key, ".pem") || strings.HasSuffix(key, ".key")
 }
 
+/**
+	Password properties are encrypted in the config store, including private and signing keys like 'jwt.secret.key' or 'jwt.keyring.<kid>.key'.
+ */
+
 func IsPasswordProperty(key string) bool {
-	return strings.HasSuffix(key, ".pwd") || strings.HasSuffix(key, ".password") || strings.HasSuffix(key, ".secret") || strings.HasSuffix(key, ".token")
+	return strings.HasSuffix(key, ".pwd") || strings.HasSuffix(key, ".password") || strings.HasSuffix(key, ".secret") || strings.HasSuffix(key, ".token") || strings.HasSuffix(key, ".key")
 }
 
 func IsHiddenProperty(key string) bool {
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"context"
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/sprintframework/sprint"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+)
+
+var ReloadableBeanClass = reflect.TypeOf((*ReloadableBean)(nil)).Elem()
+
+/**
+	Bean that applies changes of the config entries without restart of the application.
+ */
+
+type ReloadableBean interface {
+
+	/**
+	Prefixes of the config entries that bean is interested in.
+	 */
+
+	ReloadPrefixes() []string
+
+	/**
+	Calls on each change of the config entry with one of the prefixes.
+	Value is empty if the entry was removed, use glue.Properties to get the effective value.
+	 */
+
+	Reload(key, value string) error
+
+}
+
+/**
+	The purpose of this bean is to watch ConfigRepository and deliver changes to reloadable beans of the same context.
+	Add it to each context that has reloadable beans.
+ */
+
+type implPropertyReloader struct {
+	Application       sprint.Application       `inject`
+	ConfigRepository  sprint.ConfigRepository  `inject`
+	Properties        glue.Properties          `inject`
+	Log               *zap.Logger              `inject`
+
+	Beans   []ReloadableBean  `inject:"optional,level=1"`
+
+	cancelFn  context.CancelFunc
+}
+
+func PropertyReloader() glue.InitializingBean {
+	return &implPropertyReloader{}
+}
+
+func (t *implPropertyReloader) String() string {
+	return fmt.Sprintf("PropertyReloader{%d}", len(t.Beans))
+}
+
+func (t *implPropertyReloader) PostConstruct() (err error) {
+	if len(t.Beans) == 0 {
+		return nil
+	}
+	// use Application as ctx
+	t.cancelFn, err = t.ConfigRepository.Watch(t.Application, "", func(key, value string) bool {
+		t.reload(key, value)
+		return true
+	})
+	return err
+}
+
+func (t *implPropertyReloader) Destroy() error {
+	if t.cancelFn != nil {
+		t.cancelFn()
+	}
+	return nil
+}
+
+func (t *implPropertyReloader) reload(key, value string) {
+	for _, bean := range t.Beans {
+		for _, prefix := range bean.ReloadPrefixes() {
+			if strings.HasPrefix(key, prefix) {
+				if err := bean.Reload(key, value); err != nil {
+					err = ConfigMaskPolicy(t.Properties).MaskError(key, value, err)
+					t.Log.Error("PropertyReload", zap.String("key", key), zap.Any("bean", bean), zap.Error(err))
+				} else {
+					t.Log.Info("PropertyReload", zap.String("key", key), zap.Any("bean", bean))
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+/**
+	Priorities of property sources, bigger number wins:
+	  flag     100000  - command line '-p key=value'
+	  store    10000   - config repository of the core
+	  secret   100     - files of the secrets directory, '/run/secrets/<key>' by default
+	  env      10      - environment variables of the application
+	  profile  5       - 'resources:<name>-<profile>.yml' file
+	  file             - 'resources:<name>.yml' file, loaded to properties below all resolvers
+ */
+
+var ProfilePropertyPriority = 5
+
+/**
+	Optional interface of the property resolver that names the source of values for 'config sources' command.
+ */
+
+type PropertySourceNamer interface {
+
+	SourceName() string
+
+}
+
+type implProfilePropertyResolver struct {
+	profile    string
+	fileName   string
+	priority   int
+	properties map[string]string
+}
+
+func ProfilePropertyResolver(profile, fileName string, properties map[string]string, priority int) glue.PropertyResolver {
+	return &implProfilePropertyResolver{
+		profile: profile,
+		fileName: fileName,
+		priority: priority,
+		properties: properties,
+	}
+}
+
+func (t *implProfilePropertyResolver) String() string {
+	return fmt.Sprintf("ProfilePropertyResolver{%s,%s,%d}", t.profile, t.fileName, t.priority)
+}
+
+func (t *implProfilePropertyResolver) SourceName() string {
+	return "profile"
+}
+
+func (t *implProfilePropertyResolver) Priority() int {
+	return t.priority
+}
+
+func (t *implProfilePropertyResolver) GetProperty(key string) (string, bool) {
+	value, ok := t.properties[key]
+	return value, ok
+}
+
+/**
+	Loads YAML properties from the asset of the resource source with the name, the last source having the asset wins.
+	Returns nil map if the asset not found.
+ */
+
+func loadResourceProperties(beans []interface{}, sourceName, assetName string) (map[string]string, error) {
+
+	var found *glue.ResourceSource
+	walkResourceSources(beans, func(source *glue.ResourceSource) {
+		if source.Name != sourceName || source.AssetFiles == nil {
+			return
+		}
+		for _, name := range source.AssetNames {
+			if name == assetName {
+				found = source
+				return
+			}
+		}
+	})
+
+	if found == nil {
+		return nil, nil
+	}
+
+	file, err := found.AssetFiles.Open(assetName)
+	if err != nil {
+		return nil, errors.Errorf("open resource '%s:%s', %v", sourceName, assetName, err)
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, errors.Errorf("read resource '%s:%s', %v", sourceName, assetName, err)
+	}
+
+	properties, err := ParseConfig(data, ConfigFormatYAML)
+	if err != nil {
+		return nil, errors.Errorf("resource '%s:%s', %v", sourceName, assetName, err)
+	}
+	return properties, nil
+}
+
+func walkResourceSources(beans []interface{}, cb func(source *glue.ResourceSource)) {
+	for _, bean := range beans {
+		switch b := bean.(type) {
+		case *glue.ResourceSource:
+			cb(b)
+		case []interface{}:
+			walkResourceSources(b, cb)
+		}
+	}
+}
+
+/**
+	Reports all sources that have the value of the property in the order of priority, the first one is effective.
+	Values are masked by the policy, nil policy reveals them.
+ */
+
+func PropertySources(key string, properties glue.Properties, resolvers []glue.PropertyResolver, mask *MaskPolicy) string {
+
+	list := make([]glue.PropertyResolver, len(resolvers))
+	copy(list, resolvers)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority() > list[j].Priority()
+	})
+
+	maskValue := func(value string) string {
+		if mask != nil {
+			return mask.Mask(key, value)
+		}
+		return value
+	}
+
+	var out strings.Builder
+	effective := true
+	for _, resolver := range list {
+		value, ok := resolver.GetProperty(key)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprint(resolver)
+		if namer, ok := resolver.(PropertySourceNamer); ok {
+			name = namer.SourceName()
+		}
+		writePropertySource(&out, name, fmt.Sprintf("%d", resolver.Priority()), maskValue(value), effective)
+		effective = false
+	}
+
+	if effective {
+		// none of resolvers has the value, properties give it from the base file
+		if value := properties.GetString(key, ""); value != "" {
+			writePropertySource(&out, "file", "", maskValue(value), true)
+		}
+	}
+
+	if out.Len() == 0 {
+		return fmt.Sprintf("%s: not found in any source\n", key)
+	}
+	return out.String()
+}
+
+func writePropertySource(out *strings.Builder, name, priority, value string, effective bool) {
+	mark := " "
+	if effective {
+		mark = "*"
+	}
+	out.WriteString(fmt.Sprintf("%s %-8s %-7s %s\n", mark, name, priority, strings.ReplaceAll(value, "\n", " ")))
+}
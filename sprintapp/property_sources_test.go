@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"github.com/codeallergy/glue"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fileProperties struct {
+	glue.Properties
+	values map[string]string
+}
+
+func (t fileProperties) GetString(key, def string) string {
+	if value, ok := t.values[key]; ok {
+		return value
+	}
+	return def
+}
+
+type envResolver map[string]string
+
+func (t envResolver) Priority() int {
+	return 10
+}
+
+func (t envResolver) GetProperty(key string) (string, bool) {
+	value, ok := t[key]
+	return value, ok
+}
+
+func (t envResolver) SourceName() string {
+	return "env"
+}
+
+func writeResource(t *testing.T, name, content string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	return dir
+}
+
+func TestLoadResourceProperties(t *testing.T) {
+
+	base := writeResource(t, "app-dev.yml", "application:\n  port: 8080\n")
+	overlay := writeResource(t, "app-dev.yml", "application:\n  port: 8081\n  debug: true\n")
+
+	beans := []interface{}{
+		&glue.ResourceSource{Name: "resources", AssetNames: []string{"app-dev.yml"}, AssetFiles: http.Dir(base)},
+		[]interface{}{
+			&glue.ResourceSource{Name: "assets", AssetNames: []string{"app-dev.yml"}, AssetFiles: http.Dir(base)},
+			&glue.ResourceSource{Name: "resources", AssetNames: []string{"app-dev.yml"}, AssetFiles: http.Dir(overlay)},
+		},
+	}
+
+	// the last source having the asset wins
+	properties, err := loadResourceProperties(beans, "resources", "app-dev.yml")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"application.port": "8081", "application.debug": "true"}, properties)
+
+	properties, err = loadResourceProperties(beans, "resources", "app-prod.yml")
+	require.NoError(t, err)
+	require.Nil(t, properties)
+
+	broken := writeResource(t, "app-dev.yml", "application: [")
+	_, err = loadResourceProperties([]interface{}{
+		&glue.ResourceSource{Name: "resources", AssetNames: []string{"app-dev.yml"}, AssetFiles: http.Dir(broken)},
+	}, "resources", "app-dev.yml")
+	require.Error(t, err)
+}
+
+func TestPropertySources(t *testing.T) {
+
+	properties := fileProperties{values: map[string]string{
+		"application.port": "8080",
+		"application.name": "app",
+		"mail.password":    "base",
+	}}
+
+	resolvers := []glue.PropertyResolver{
+		ProfilePropertyResolver("dev", "app-dev.yml", map[string]string{"application.port": "8081", "mail.password": "profile"}, ProfilePropertyPriority),
+		envResolver{"application.port": "9090"},
+	}
+
+	require.Equal(t, "* env      10      9090\n  profile  5       8081\n", PropertySources("application.port", properties, resolvers, NewMaskPolicy("")))
+	require.Equal(t, "* profile  5       ******\n", PropertySources("mail.password", properties, resolvers, NewMaskPolicy("")))
+	require.Equal(t, "* profile  5       profile\n", PropertySources("mail.password", properties, resolvers, nil))
+	require.Equal(t, "* file             app\n", PropertySources("application.name", properties, resolvers, NewMaskPolicy("")))
+	require.Equal(t, "application.version: not found in any source\n", PropertySources("application.version", properties, resolvers, nil))
+}
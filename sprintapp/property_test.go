@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPropertyEnvName(t *testing.T) {
+
+	// names of application properties are the same as before the full mapping
+	require.Equal(t, "SPRINT_BOOT", sprintapp.PropertyEnvName("sprint", "application.boot"))
+	require.Equal(t, "SPRINT_LOG_LEVEL", sprintapp.PropertyEnvName("sprint", "application.log.level"))
+	require.Equal(t, "SPRINT_FOO-BAR", sprintapp.PropertyEnvName("sprint", "application.foo-bar"))
+
+	require.Equal(t, "SPRINT_CONTROL_GRPC_SERVER_BIND_ADDRESS", sprintapp.PropertyEnvName("sprint", "control-grpc-server.bind-address"))
+	require.Equal(t, "SPRINT_JOB_*_CONCURRENCY", sprintapp.PropertyEnvName("sprint", "job.*.concurrency"))
+}
+
+func TestPropertyEnvMapping(t *testing.T) {
+
+	var keys []string
+	for _, def := range sprintapp.DefaultPropertySchema().PropertyDefs() {
+		keys = append(keys, def.Key)
+	}
+
+	mapping, err := sprintapp.PropertyEnvMapping("sprint", keys)
+	require.NoError(t, err)
+	require.Equal(t, len(keys), len(mapping))
+
+	// the first property owns the name
+	mapping, err = sprintapp.PropertyEnvMapping("sprint", []string{"a.b", "a-b", "application.boot", "boot"})
+	require.Error(t, err)
+	require.Equal(t, map[string]string{"a.b": "SPRINT_A_B", "application.boot": "SPRINT_BOOT"}, mapping)
+}
+
+func TestUnknownEnviron(t *testing.T) {
+
+	t.Setenv("SPRINT_ENV_MAPPING", "all")
+	t.Setenv("SPRINT_PROFILE", "dev")
+	t.Setenv("SPRINT_BOOT", "token")
+	t.Setenv("SPRINT_BOTO", "token")
+	t.Setenv("SPRINT_JOB_BACKUP_CONCURRENCY", "skip")
+	t.Setenv("SPRINT_JOB_BACKUP_CONCURENCY", "skip")
+	t.Setenv("SPRINT_JWT_ISSUER", "sprint")
+
+	resolver := sprintapp.SystemEnvironmentPropertyResolver("sprint", 10)
+	inspector, ok := resolver.(sprintapp.EnvironmentInspector)
+	require.True(t, ok)
+
+	// requested properties are known
+	value, ok := resolver.GetProperty("jwt.issuer")
+	require.True(t, ok)
+	require.Equal(t, "sprint", value)
+
+	unknown := inspector.UnknownEnviron([]string{"application.boot", "job.*.concurrency"})
+	require.Equal(t, []string{"SPRINT_BOTO", "SPRINT_JOB_BACKUP_CONCURENCY"}, unknown)
+}
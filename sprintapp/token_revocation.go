@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintapp
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var TokenRevocationListClass = reflect.TypeOf((*TokenRevocationList)(nil)).Elem()
+
+/**
+	Revoked JWT token, the record is kept until the token expires.
+ */
+
+type RevokedToken struct {
+	Id        string  `json:"id"`
+	User      string  `json:"user"`
+	ExpiresAt int64   `json:"expiresAt"`  // unix seconds, zero if expiration is unknown
+	RevokedAt int64   `json:"revokedAt"`  // unix seconds
+}
+
+/**
+	Persistent list of revoked tokens keyed by JWT ID, shared between nodes of the same store.
+ */
+
+type TokenRevocationList interface {
+
+	/**
+	Revokes the token by JWT ID on behalf of the user, the record is removed by GC after expiresAt.
+	Zero expiresAt keeps the record forever.
+	 */
+
+	Revoke(id string, expiresAt int64, username string) error
+
+	IsRevoked(id string) (bool, error)
+
+	/**
+	Lists not expired revocations, including revocations that never expire.
+	 */
+
+	List() ([]*RevokedToken, error)
+
+	/**
+	Removes expired revocations, returns the number of removed records.
+	 */
+
+	PurgeExpired() (int, error)
+
+}
+
+/**
+	Revokes the JWT token or JWT ID, returns the revoked JWT ID.
+	Revocations of tokens without expiration and of raw JWT IDs never expire, because the token stays valid as long as it is not revoked.
+ */
+
+func RevokeAuthToken(list TokenRevocationList, tokenOrId, username string) (string, error) {
+
+	id := strings.TrimSpace(tokenOrId)
+	if id == "" {
+		return "", errors.New("empty token or JWT ID")
+	}
+
+	var expiresAt int64
+	if sprintutils.IsJwtToken(id) {
+		tokenId, tokenExpiresAt, err := sprintutils.ParseAuthTokenId(id)
+		if err != nil {
+			return "", err
+		}
+		if tokenId == "" {
+			return "", errors.New("token does not have JWT ID")
+		}
+		id = tokenId
+		expiresAt = tokenExpiresAt
+	}
+
+	if err := list.Revoke(id, expiresAt, username); err != nil {
+		return "", errors.Errorf("revoke token '%s', %v", id, err)
+	}
+	return id, nil
+}
+
+func FormatRevokedTokens(list []*RevokedToken) string {
+	var out strings.Builder
+	for _, r := range list {
+		expires := "never"
+		if r.ExpiresAt > 0 {
+			expires = time.Unix(r.ExpiresAt, 0).Format(time.RFC3339)
+		}
+		out.WriteString(fmt.Sprintf("%s, user '%s', revoked %s, expires %s\n", r.Id, r.User, time.Unix(r.RevokedAt, 0).Format(time.RFC3339), expires))
+	}
+	return out.String()
+}
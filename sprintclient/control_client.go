@@ -146,6 +146,101 @@ func (t *implControlClient) JobCommand(command string, args []string) (string, e
 	}
 }
 
+/**
+	Runs the job on the server and writes the job output until the job finishes.
+ */
+
+func (t *implControlClient) JobFollow(name string, writer io.StringWriter) error {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := t.GrpcConn.NewStream(ctx, &sprintutils.JobFollowStream, sprintutils.ControlStreamMethod(&sprintutils.JobFollowStream))
+	if err != nil {
+		return t.wrapError(err)
+	}
+
+	req := &sprintpb.Command {
+		Command: "run",
+		Args: []string{ name },
+	}
+
+	if err := stream.SendMsg(req); err != nil {
+		return t.wrapError(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return t.wrapError(err)
+	}
+
+	for {
+		resp := new(sprintpb.CommandResult)
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		writer.WriteString(resp.Content + "\n")
+	}
+}
+
+/**
+	Watches changes of the config entries with the prefix and writes them until the server closes the stream.
+ */
+
+func (t *implControlClient) WatchConfig(prefix string, writer io.StringWriter) error {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := t.GrpcConn.NewStream(ctx, &sprintutils.WatchConfigStream, sprintutils.ControlStreamMethod(&sprintutils.WatchConfigStream))
+	if err != nil {
+		return t.wrapError(err)
+	}
+
+	req := &sprintpb.Command {
+		Command: "watch",
+		Args: []string{ prefix },
+	}
+
+	if err := stream.SendMsg(req); err != nil {
+		return t.wrapError(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return t.wrapError(err)
+	}
+
+	for {
+		resp := new(sprintpb.CommandResult)
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		writer.WriteString(resp.Content + "\n")
+	}
+}
+
+/**
+	Executes auth command on the control service extension, for example token revocation.
+ */
+
+func (t *implControlClient) AuthCommand(command string, args []string) (string, error) {
+
+	req := &sprintpb.Command {
+		Command: command,
+		Args: args,
+	}
+
+	resp := new(sprintpb.CommandResult)
+	if err := t.GrpcConn.Invoke(context.Background(), sprintutils.ControlUnaryMethod(sprintutils.AuthMethodName), req, resp); err != nil {
+		return "", t.wrapError(err)
+	}
+	return resp.Content, nil
+}
 
 func (t *implControlClient) StorageCommand(command string, args []string) (string, error) {
 
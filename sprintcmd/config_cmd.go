@@ -12,12 +12,14 @@ import (
 	"github.com/sprintframework/sprint"
 	"github.com/sprintframework/sprintframework/sprintapp"
 	"github.com/sprintframework/sprintframework/sprintutils"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"io"
 	"io/ioutil"
 	"math"
 	"os"
+	"os/user"
 	"strconv"
 	"strings"
 )
@@ -27,8 +29,30 @@ type implConfigCommand struct {
 	Application sprint.Application `inject`
 }
 
+type configWatchClient interface {
+	WatchConfig(prefix string, writer io.StringWriter) error
+}
+
 type coreConfigContext struct {
 	ConfigRepository sprint.ConfigRepository `inject`
+	Properties       glue.Properties         `inject`
+	Log              *zap.Logger             `inject:"optional"`
+
+	PropertyResolvers []glue.PropertyResolver `inject:"optional,level=-1"`
+}
+
+/**
+	Gets the mask policy of the core config, nil on reveal, that is audited.
+ */
+
+func (t *coreConfigContext) maskPolicy(reveal bool, command, key string) *sprintapp.MaskPolicy {
+	if !reveal {
+		return sprintapp.ConfigMaskPolicy(t.Properties)
+	}
+	if t.Log != nil {
+		t.Log.Warn("ConfigReveal", zap.String("command", command), zap.String("key", key), zap.String("user", localUsername()), zap.Bool("offline", true))
+	}
+	return nil
 }
 
 func ConfigCommand() sprint.Command {
@@ -47,20 +71,39 @@ Usage: ./%s config [command]
 
 Commands:
 
-  get                      Gets the config entry by key.
+  get                      Gets the config entry by key, usage: get key [--reveal].
 
   set                      Sets the config entry value by key.
 
-  list                     List all config entries, hides the passwords and keys.
+  list                     List all config entries, hides the passwords and keys, usage: list [prefix] [limit] [--reveal].
+
+  dump                     Dumps all config entries to move to another system, usage: dump [prefix] [--reveal].
+
+  history                  Shows versioned changes of the config entry by key.
 
-  dump                     Dumps all config entries to move to another system. Needs admin permissions.
+  rollback                 Sets the config entry by key to the value of the version from history.
+
+  describe                 Shows type, default value and description of the config entry by key.
+
+  sources                  Shows sources (flag, store, env, profile, file) of the config entry by key, usage: sources key [--reveal].
+
+  env                      Lists environment variable names of the known or given config entries, usage: env [key...].
+
+  watch                    Prints changes of the config entries with the prefix until interrupted.
+
+  export                   Exports config entries with the prefix, usage: export [--format yaml|json] [prefix] [--reveal].
+
+  import                   Imports config entries from the file, usage: import file [--dry-run] [--merge|--replace].
+
+Sensitive values (hidden, password, PEM and 'application.mask.patterns' keys) are masked,
+--reveal shows them to ADMIN only and every reveal is audited.
 
 `
 	return strings.TrimSpace(fmt.Sprintf(helpText, t.Application.Executable()))
 }
 
 func (t *implConfigCommand) Synopsis() string {
-	return "config commands: [get, set, dump, list]"
+	return "config commands: [get, set, dump, list, history, rollback, describe, sources, env, watch, export, import]"
 }
 
 func (t *implConfigCommand) Run(args []string) error {
@@ -79,6 +122,30 @@ func (t *implConfigCommand) Run(args []string) error {
 	case "dump", "list":
 		return t.dumpConfig(cmd, args)
 
+	case "history":
+		return t.configHistory(args)
+
+	case "rollback":
+		return t.rollbackConfig(args)
+
+	case "describe":
+		return t.describeConfig(args)
+
+	case "sources":
+		return t.configSources(args)
+
+	case "env":
+		return t.configEnv(args)
+
+	case "watch":
+		return t.watchConfig(args)
+
+	case "export":
+		return t.exportConfig(args)
+
+	case "import":
+		return t.importConfig(args)
+
 	default:
 		return errors.Errorf("unknown sub-command for config '%s'", cmd)
 	}
@@ -87,18 +154,24 @@ func (t *implConfigCommand) Run(args []string) error {
 }
 
 func (t *implConfigCommand) getConfig(args []string) error {
+	args, reveal := sprintapp.ExtractRevealFlag(args)
 	if len(args) < 1 {
 		return errors.Errorf("'config get' command expected key argument: %v", args)
 	}
 	key := args[0]
 
+	cmdArgs := []string {key}
+	if reveal {
+		cmdArgs = append(cmdArgs, sprintapp.RevealFlag)
+	}
+
 	var value string
 	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
-		value, err = client.ConfigCommand("get", []string {key})
+		value, err = client.ConfigCommand("get", cmdArgs)
 		return
 	})
 	if err != nil && status.Code(err) == codes.Unavailable {
-		value, err = t.getFromStorage(key)
+		value, err = t.getFromStorage(key, reveal)
 	}
 	if err != nil {
 		return err
@@ -176,6 +249,8 @@ func (t *implConfigCommand) dumpConfig(cmd string, args []string) error {
 
 func (t *implConfigCommand) dumpFromStorage(cmd string, args []string, writer io.StringWriter) (err error) {
 
+	args, reveal := sprintapp.ExtractRevealFlag(args)
+
 	var prefix string
 	if len(args) > 0 {
 		prefix = args[0]
@@ -196,21 +271,308 @@ func (t *implConfigCommand) dumpFromStorage(cmd string, args []string, writer io
 
 	c := new(coreConfigContext)
 	return doInCore(t.Context, c, func(core glue.Context) error {
-		return c.ConfigRepository.EnumerateAll(prefix, func(key, value string) bool {
-			if len(value) > limit {
-				value = value[:limit] + "..."
-				value = strings.ReplaceAll(value, "\n", " ")
+		return sprintapp.DumpConfig(c.ConfigRepository, prefix, limit, c.maskPolicy(reveal, cmd, prefix), writer)
+	})
+}
+
+func (t *implConfigCommand) configHistory(args []string) error {
+	if len(args) < 1 {
+		return errors.Errorf("'config history' command expected key argument: %v", args)
+	}
+	key := args[0]
+
+	var content string
+	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		content, err = client.ConfigCommand("history", []string{key})
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		content, err = t.historyFromStorage(key)
+	}
+	if err != nil {
+		return err
+	}
+	println(content)
+	return nil
+}
+
+func (t *implConfigCommand) rollbackConfig(args []string) error {
+	if len(args) < 2 {
+		return errors.Errorf("'config rollback' command expected key and version arguments: %v", args)
+	}
+	key := args[0]
+	version := args[1]
+
+	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) error {
+		_, err := client.ConfigCommand("rollback", []string{key, version})
+		return err
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		fmt.Printf("Error on gRPC: %v\n", err)
+		err = t.rollbackInStorage(key, version)
+	}
+	if err != nil {
+		return err
+	}
+	println("SUCCESS")
+	return nil
+}
+
+func (t *implConfigCommand) describeConfig(args []string) error {
+	if len(args) < 1 {
+		return errors.Errorf("'config describe' command expected key argument: %v", args)
+	}
+	key := args[0]
+
+	var content string
+	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		content, err = client.ConfigCommand("describe", []string{key})
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		content, err = t.describeFromStorage(key)
+	}
+	if err != nil {
+		return err
+	}
+	println(content)
+	return nil
+}
+
+func (t *implConfigCommand) configSources(args []string) error {
+	args, reveal := sprintapp.ExtractRevealFlag(args)
+	if len(args) < 1 {
+		return errors.Errorf("'config sources' command expected key argument: %v", args)
+	}
+	key := args[0]
+
+	cmdArgs := []string{key}
+	if reveal {
+		cmdArgs = append(cmdArgs, sprintapp.RevealFlag)
+	}
+
+	var content string
+	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		content, err = client.ConfigCommand("sources", cmdArgs)
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		c := new(coreConfigContext)
+		err = doInCore(t.Context, c, func(core glue.Context) error {
+			content = sprintapp.PropertySources(key, c.Properties, c.PropertyResolvers, c.maskPolicy(reveal, "sources", key))
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	print(content)
+	return nil
+}
+
+/**
+	Environment names are local knowledge, the command does not need the running node or the core.
+ */
+
+func (t *implConfigCommand) configEnv(args []string) error {
+
+	name := t.Application.Name()
+	mappingVar := sprintapp.EnvMappingVariable(name)
+	mapping := strings.ToLower(os.Getenv(mappingVar))
+	if mapping != sprintapp.EnvMappingAll {
+		mapping = sprintapp.EnvMappingApplication
+	}
+
+	keys := args
+	if len(keys) == 0 {
+		for _, def := range sprintapp.DefaultPropertySchema().PropertyDefs() {
+			keys = append(keys, def.Key)
+		}
+	}
+
+	envs, collision := sprintapp.PropertyEnvMapping(name, keys)
+
+	fmt.Printf("Environment mapping '%s', set %s=%s to map all properties\n", mapping, mappingVar, sprintapp.EnvMappingAll)
+	for _, key := range keys {
+		env, ok := envs[key]
+		if !ok {
+			env = sprintapp.PropertyEnvName(name, key) + " (collision)"
+		} else if !sprintapp.IsEnvMapped(mapping, key) {
+			env += " (not mapped)"
+		}
+		fmt.Printf("%-40s %s\n", key, env)
+	}
+	return collision
+}
+
+func (t *implConfigCommand) watchConfig(args []string) error {
+	var prefix string
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	return sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) error {
+		watcher, ok := client.(configWatchClient)
+		if !ok {
+			return errors.New("control client does not support config watch")
+		}
+		return watcher.WatchConfig(prefix, os.Stdout)
+	})
+}
+
+func (t *implConfigCommand) exportConfig(args []string) error {
+
+	args, reveal := sprintapp.ExtractRevealFlag(args)
+
+	format := sprintapp.ConfigFormatYAML
+	var prefix string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format":
+			if i+1 == len(args) {
+				return errors.New("'config export' command expected value of --format flag")
 			}
-			writer.WriteString(fmt.Sprintf("%s: %s\n", key, value))
-			return true
+			i++
+			format = args[i]
+		default:
+			prefix = args[i]
+		}
+	}
+
+	cmdArgs := []string{format, prefix}
+	if reveal {
+		cmdArgs = append(cmdArgs, sprintapp.RevealFlag)
+	}
+
+	var content string
+	err := sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		content, err = client.ConfigCommand("export", cmdArgs)
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		c := new(coreConfigContext)
+		err = doInCore(t.Context, c, func(core glue.Context) error {
+			data, err := sprintapp.ExportConfig(c.ConfigRepository, prefix, format, c.maskPolicy(reveal, "export", prefix))
+			content = string(data)
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString(content)
+	return nil
+}
+
+func (t *implConfigCommand) importConfig(args []string) error {
+
+	mode := sprintapp.ConfigImportMerge
+	dryRun := false
+	var fileName string
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run":
+			dryRun = true
+		case "--merge":
+			mode = sprintapp.ConfigImportMerge
+		case "--replace":
+			mode = sprintapp.ConfigImportReplace
+		default:
+			fileName = arg
+		}
+	}
+
+	if fileName == "" {
+		return errors.Errorf("'config import' command expected file argument: %v", args)
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return errors.Errorf("i/o error on reading config from file '%s', %v", fileName, err)
+	}
+	format := sprintapp.ConfigFormatOf(fileName)
+
+	var report string
+	err = sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		report, err = client.ConfigCommand("import", []string{format, mode, strconv.FormatBool(dryRun), string(data)})
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		entries, err := sprintapp.ParseConfig(data, format)
+		if err != nil {
+			return err
+		}
+		c := new(coreConfigContext)
+		err = doInCore(t.Context, c, func(core glue.Context) (err error) {
+			report, err = sprintapp.ImportConfig(c.ConfigRepository, entries, mode, dryRun, localUsername())
+			return
 		})
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	print(report)
+	return nil
+}
+
+func (t *implConfigCommand) describeFromStorage(key string) (content string, err error) {
+	c := new(coreConfigContext)
+	err = doInCore(t.Context, c, func(core glue.Context) error {
+		resolver, ok := c.ConfigRepository.(sprintapp.PropertySchemaResolver)
+		if !ok {
+			return errors.New("config repository does not support schema")
+		}
+		def, ok := resolver.DescribeProperty(key)
+		if !ok {
+			return errors.Errorf("schema not found for config entry '%s'", key)
+		}
+		content = def.String()
+		return nil
 	})
+	return
 }
 
-func (t *implConfigCommand) getFromStorage(key string) (value string, err error) {
+func (t *implConfigCommand) historyFromStorage(key string) (content string, err error) {
+	c := new(coreConfigContext)
+	err = doInCore(t.Context, c, func(core glue.Context) error {
+		versioned, ok := c.ConfigRepository.(sprintapp.VersionedConfigRepository)
+		if !ok {
+			return errors.New("config repository does not keep history")
+		}
+		history, err := versioned.History(key)
+		if err != nil {
+			return err
+		}
+		content = sprintapp.FormatConfigHistory(key, history, sprintapp.ConfigMaskPolicy(c.Properties))
+		return nil
+	})
+	return
+}
+
+func (t *implConfigCommand) rollbackInStorage(key, versionStr string) error {
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil {
+		return errors.Errorf("parsing version '%s', %v", versionStr, err)
+	}
+	c := new(coreConfigContext)
+	return doInCore(t.Context, c, func(core glue.Context) error {
+		versioned, ok := c.ConfigRepository.(sprintapp.VersionedConfigRepository)
+		if !ok {
+			return errors.New("config repository does not keep history")
+		}
+		return versioned.Rollback(key, version, localUsername())
+	})
+}
+
+func (t *implConfigCommand) getFromStorage(key string, reveal bool) (value string, err error) {
 	c := new(coreConfigContext)
 	err = doInCore(t.Context, c, func(core glue.Context) error {
 		value, err = c.ConfigRepository.Get(key)
+		if mask := c.maskPolicy(reveal, "get", key); mask != nil {
+			value = mask.Mask(key, value)
+		}
 		return err
 	})
 	return
@@ -219,6 +581,20 @@ func (t *implConfigCommand) getFromStorage(key string) (value string, err error)
 func (t *implConfigCommand) setInStorage(key, value string) error {
 	c := new(coreConfigContext)
 	return doInCore(t.Context, c, func(core glue.Context) error {
+		if versioned, ok := c.ConfigRepository.(sprintapp.VersionedConfigRepository); ok {
+			return versioned.SetAs(key, value, localUsername())
+		}
 		return c.ConfigRepository.Set(key, value)
 	})
 }
+
+/**
+	Offline changes are recorded on behalf of the OS user.
+ */
+
+func localUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
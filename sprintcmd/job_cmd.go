@@ -10,9 +10,15 @@ import (
 	"github.com/codeallergy/glue"
 	"github.com/sprintframework/sprint"
 	"github.com/pkg/errors"
+	"io"
+	"os"
 	"strings"
 )
 
+type jobFollowClient interface {
+	JobFollow(name string, writer io.StringWriter) error
+}
+
 type implJobsCommand struct {
 	Application sprint.Application `inject`
 	Context glue.Context `inject`
@@ -34,18 +40,24 @@ Usage: ./%s jobs [command]
 
 Commands:
 
-  list                      Gets the schedule list of all jobs.
+  list                      Gets the schedule list of all jobs with the next and last fire times.
+
+  run                       Run a job by name. Use '--follow' flag to stream the job output and the result.
+
+  cancel                    Cancel the running job, the job stays in the schedule list.
 
-  run                       Run a job by name.
+  remove                    Remove the job from the schedule list.
 
-  cancel                    Cancel the running job and remove from schedule list.
+  status                    Shows the running executions and attempts of the last execution of the job.
+
+  history                   Shows the latest executions of the job with status, duration and errors.
 
 `
 	return strings.TrimSpace(fmt.Sprintf(helpText, t.Application.Executable()))
 }
 
 func (t *implJobsCommand) Synopsis() string {
-	return "jobs management - [list, run, cancel]"
+	return "jobs management - [list, run, cancel, remove, status, history]"
 }
 
 func (t *implJobsCommand) Run(args []string) error {
@@ -57,6 +69,10 @@ func (t *implJobsCommand) Run(args []string) error {
 	command := args[0]
 	args = args[1:]
 
+	if command == "run" && hasFollowFlag(args) {
+		return t.followJob(removeFollowFlag(args))
+	}
+
 	return sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) error {
 		output, err := client.JobCommand(command, args)
 		if err != nil {
@@ -66,4 +82,39 @@ func (t *implJobsCommand) Run(args []string) error {
 		return nil
 	})
 
-}
\ No newline at end of file
+}
+
+func (t *implJobsCommand) followJob(args []string) error {
+
+	if len(args) < 1 {
+		return errors.New("Usage: job run name --follow")
+	}
+	jobName := args[0]
+
+	return sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) error {
+		follower, ok := client.(jobFollowClient)
+		if !ok {
+			return errors.New("control client does not support job output streaming")
+		}
+		return follower.JobFollow(jobName, os.Stdout)
+	})
+}
+
+func hasFollowFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--follow" || arg == "-f" {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFollowFlag(args []string) []string {
+	var list []string
+	for _, arg := range args {
+		if arg != "--follow" && arg != "-f" {
+			list = append(list, arg)
+		}
+	}
+	return list
+}
@@ -6,12 +6,15 @@
 package sprintcmd
 
 import (
-	"encoding/base64"
 	"fmt"
 	"github.com/codeallergy/glue"
 	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
 	"github.com/sprintframework/sprintframework/sprintutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +24,30 @@ type implKeygenCommand struct {
 	Application      sprint.Application      `inject`
 	ApplicationFlags sprint.ApplicationFlags `inject`
 	Properties       glue.Properties      `inject`
+	Context          glue.Context         `inject`
+
+	SystemEnvironmentPropertyResolver sprint.SystemEnvironmentPropertyResolver `inject`
+}
+
+type authCommandClient interface {
+	AuthCommand(command string, args []string) (string, error)
+}
+
+type coreRevocationContext struct {
+	RevocationList  sprintapp.TokenRevocationList  `inject`
+}
+
+type coreKeyringContext struct {
+	ConfigRepository  sprint.ConfigRepository  `inject`
+}
+
+type coreNodeContext struct {
+	NodeService  sprint.NodeService  `inject`
+}
+
+type coreBootContext struct {
+	ConfigRepository        sprint.ConfigRepository           `inject`
+	EncryptedStoreRegistry  sprintcore.EncryptedStoreRegistry `inject:"optional"`
 }
 
 func KeygenCommand() sprint.Command {
@@ -41,16 +68,29 @@ Commands:
 
   boot                      Generates the bootstrap token using for decryption of configs and databases.
 
+  rotate-boot               Re-keys encrypted stores and config secrets with the new bootstrap token, node must be stopped.
+                            Usage: rotate-boot [new-token], generates the new token if not provided.
+                            The first start with the new token completes the rotation.
+
   auth                      Generates JWT authorization token using in gRPC and HTTPS.
 
   verify                    Verify the JWT token and decodes arguments.
 
+  revoke                    Revokes the JWT token or JWT ID on all nodes of the store, usage: revoke <token|jti>.
+
+  revoked                   Lists revoked JWT IDs that are not expired yet.
+
+  rotate-jwt                Adds the new JWT signing key to the keyring and makes it active, issued tokens stay valid.
+                            Usage: rotate-jwt [HS256|RS256|ES256|EdDSA], HS256 by default.
+
+  retire-jwt                Removes the JWT key from the keyring once its tokens have expired, usage: retire-jwt <kid|legacy>.
+
 `
 	return strings.TrimSpace(fmt.Sprintf(helpText, t.Application.Executable()))
 }
 
 func (t *implKeygenCommand) Synopsis() string {
-	return "keygen commands [boot, auth, verify]"
+	return "keygen commands [boot, rotate-boot, auth, verify, revoke, revoked, rotate-jwt, retire-jwt]"
 }
 
 func (t *implKeygenCommand) Run(args []string) (err error) {
@@ -77,10 +117,20 @@ func (t *implKeygenCommand) Run(args []string) (err error) {
 	switch cmd {
 	case "boot":
 		return t.generateBootstrapToken(args)
+	case "rotate-boot":
+		return t.rotateBootstrapToken(args)
 	case "auth":
 		return t.generateAuthToken(args)
 	case "verify":
 		return t.verifyAuthToken(args)
+	case "revoke":
+		return t.revokeAuthToken(args)
+	case "revoked":
+		return t.listRevokedTokens()
+	case "rotate-jwt":
+		return t.rotateJwtKey(args)
+	case "retire-jwt":
+		return t.retireJwtKey(args)
 	default:
 		return errors.Errorf("unknown sub-command '%s' for token command", cmd)
 	}
@@ -95,6 +145,95 @@ func (t *implKeygenCommand) generateBootstrapToken(args []string) error {
 	}
 }
 
+/**
+	Rotation is crash-safe: till the first start with the new token both tokens open stores and secrets,
+	the first start with one of them completes or rolls back the rotation.
+	The new token is shown and confirmed by the operator before any store gets changed.
+ */
+
+func (t *implKeygenCommand) rotateBootstrapToken(args []string) error {
+
+	oldToken := t.Properties.GetString("application.boot", "")
+	if oldToken == "" {
+		var ok bool
+		oldToken, ok = t.SystemEnvironmentPropertyResolver.PromptProperty("application.boot")
+		if !ok || oldToken == "" {
+			return errors.New("'application.boot' bootstrap token is required")
+		}
+	}
+
+	oldKey, err := sprintutils.ParseToken(oldToken)
+	if err != nil {
+		return errors.Errorf("invalid old bootstrap token, %v", err)
+	}
+
+	var newToken string
+	if len(args) > 0 {
+		newToken = args[0]
+	} else {
+		if newToken, err = sprintutils.GenerateToken(); err != nil {
+			return err
+		}
+		fmt.Printf("New bootstrap token, save it before the rotation:\n%s\n", newToken)
+		answer := sprintutils.Prompt("Type 'yes' when the new token is saved: ")
+		if answer != "yes" {
+			return errors.New("rotation cancelled, nothing has changed")
+		}
+	}
+
+	newKey, err := sprintutils.ParseToken(newToken)
+	if err != nil {
+		return errors.Errorf("invalid new bootstrap token, %v", err)
+	}
+
+	if newToken == oldToken {
+		return errors.New("new bootstrap token is the same as the old one")
+	}
+
+	var keyDirs map[string]string
+	c := new(coreBootContext)
+	err = doInCore(t.Context, c, func(core glue.Context) (err error) {
+		// stores are resolved before the change of secrets, rotation of a part of them is not recoverable
+		keyDirs, err = sprintcore.EncryptedStoreKeyDirs(core, c.EncryptedStoreRegistry)
+		if err != nil {
+			return err
+		}
+		secrets, ok := c.ConfigRepository.(sprintapp.SecretConfigRepository)
+		if !ok {
+			return nil
+		}
+		n, err := secrets.RotateSecretKey(newToken)
+		if err != nil {
+			return errors.Errorf("rotate config secrets, %v", err)
+		}
+		fmt.Printf("Re-encrypted %d config secrets\n", n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// stores are closed at this point
+	for name, dir := range keyDirs {
+		// leftovers of the interrupted rotation
+		if _, err := sprintcore.RecoverBadgerKeyRotation(dir, oldKey); err != nil {
+			return errors.Errorf("recover key rotation of '%s', %v", name, err)
+		}
+		if err := sprintcore.PrepareBadgerKeyRotation(dir, oldKey, newKey); err != nil {
+			return errors.Errorf("prepare key rotation of '%s', %v", name, err)
+		}
+	}
+	for name, dir := range keyDirs {
+		if err := sprintcore.CommitBadgerKeyRotation(dir); err != nil {
+			return errors.Errorf("commit key rotation of '%s', %v", name, err)
+		}
+		fmt.Printf("Re-keyed store '%s'\n", name)
+	}
+
+	fmt.Printf("Set the new bootstrap token to %s environment variable and start the node to complete the rotation, the old token works until then.\n", sprintapp.PropertyEnvName(t.Application.Name(), "application.boot"))
+	return nil
+}
+
 func (t *implKeygenCommand) generateAuthToken(args []string) error {
 
 	if len(args) < 4 {
@@ -117,8 +256,7 @@ func (t *implKeygenCommand) generateAuthToken(args []string) error {
 		}
 	}
 
-	secret := sprintutils.PromptPassword("Enter JWT secret key: ")
-	secretKey, err := base64.RawURLEncoding.DecodeString(secret)
+	keyring, err := t.promptKeyring()
 	if err != nil {
 		return err
 	}
@@ -140,7 +278,19 @@ func (t *implKeygenCommand) generateAuthToken(args []string) error {
 		ExpiresAt: time.Now().Unix() + ttlDays*24*3600,
 	}
 
-	token, err := sprintutils.GenerateAuthToken(secretKey, user)
+	// JWT ID is issued in the same way as by the running node, so it is unique across the cluster
+	var tokenId string
+	c := new(coreNodeContext)
+	err = doInCore(t.Context, c, func(core glue.Context) error {
+		tokenId = c.NodeService.Issue().String()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	policy := sprintapp.AuthTokenPolicy(t.Application.Name(), t.Properties)
+	token, err := sprintutils.GenerateAuthToken(keyring, tokenId, user, policy)
 	if err != nil {
 		return err
 	}
@@ -149,6 +299,23 @@ func (t *implKeygenCommand) generateAuthToken(args []string) error {
 	return nil
 }
 
+/**
+	Empty key ID selects the legacy 'jwt.secret.key', tokens are signed without 'kid' header.
+ */
+
+func (t *implKeygenCommand) promptKeyring() (*sprintutils.AuthKeyring, error) {
+	kid := sprintutils.Prompt("Enter JWT key id (empty for jwt.secret.key): ")
+	if kid == sprintapp.LegacyKid {
+		kid = ""
+	}
+	secret := sprintutils.PromptPassword("Enter JWT secret key: ")
+	key, err := sprintutils.ParseAuthKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return sprintutils.SingleKeyring(kid, key), nil
+}
+
 func (t *implKeygenCommand) verifyAuthToken(args []string) error {
 
 	var authToken string
@@ -163,17 +330,103 @@ func (t *implKeygenCommand) verifyAuthToken(args []string) error {
 		return errors.New("auth token not found")
 	}
 
-	secret := sprintutils.PromptPassword("Enter JWT secret key: ")
-	secretKey, err := base64.RawURLEncoding.DecodeString(secret)
+	keyring, err := t.promptKeyring()
 	if err != nil {
 		return err
 	}
 
-	user, err := sprintutils.VerifyAuthToken(secretKey, authToken)
+	policy := sprintapp.AuthTokenPolicy(t.Application.Name(), t.Properties)
+	user, err := sprintutils.VerifyAuthToken(keyring, authToken, policy)
 	if err != nil {
-		errors.Errorf("verify error, %v", err)
+		return errors.Errorf("verify error, %v", err)
 	}
 
 	fmt.Printf("%s, %+v, %s, expires at %s\n", user.Username, user.Roles, user.Context, time.Unix(user.ExpiresAt, 0).String())
 	return nil
 }
+
+/**
+	Runs the auth command on the running node, falls back to the core of the stopped node.
+ */
+
+func (t *implKeygenCommand) doAuthCommand(command string, args []string, c interface{}, offline func() (string, error)) (content string, err error) {
+	err = sprint.DoWithControlClient(t.Context, func(client sprint.ControlClient) (err error) {
+		auth, ok := client.(authCommandClient)
+		if !ok {
+			return errors.New("control client does not support auth commands")
+		}
+		content, err = auth.AuthCommand(command, args)
+		return
+	})
+	if err != nil && status.Code(err) == codes.Unavailable {
+		err = doInCore(t.Context, c, func(core glue.Context) (err error) {
+			content, err = offline()
+			return
+		})
+	}
+	return
+}
+
+func (t *implKeygenCommand) revokeAuthToken(args []string) error {
+	if len(args) < 1 {
+		return errors.Errorf("'keygen revoke' command expected token or JWT ID argument: %v", args)
+	}
+	tokenOrId := args[0]
+
+	c := new(coreRevocationContext)
+	id, err := t.doAuthCommand("revoke", []string{tokenOrId}, c, func() (string, error) {
+		return sprintapp.RevokeAuthToken(c.RevocationList, tokenOrId, localUsername())
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Revoked %s\n", id)
+	return nil
+}
+
+func (t *implKeygenCommand) listRevokedTokens() error {
+	c := new(coreRevocationContext)
+	content, err := t.doAuthCommand("revoked", nil, c, func() (string, error) {
+		list, err := c.RevocationList.List()
+		return sprintapp.FormatRevokedTokens(list), err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Print(content)
+	return nil
+}
+
+func (t *implKeygenCommand) rotateJwtKey(args []string) error {
+	var alg string
+	if len(args) > 0 {
+		alg = args[0]
+	}
+
+	c := new(coreKeyringContext)
+	kid, err := t.doAuthCommand("rotate-jwt", args, c, func() (string, error) {
+		return sprintapp.RotateAuthKey(c.ConfigRepository, alg, localUsername())
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("New JWT signing key '%s' is active, retire the previous key after its tokens expire\n", kid)
+	return nil
+}
+
+func (t *implKeygenCommand) retireJwtKey(args []string) error {
+	if len(args) < 1 {
+		return errors.Errorf("'keygen retire-jwt' command expected key id argument: %v", args)
+	}
+	kid := args[0]
+
+	c := new(coreKeyringContext)
+	_, err := t.doAuthCommand("retire-jwt", []string{kid}, c, func() (string, error) {
+		return kid, sprintapp.RetireAuthKey(c.ConfigRepository, kid, localUsername())
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Retired JWT key '%s'\n", kid)
+	return nil
+}
@@ -10,15 +10,19 @@ import (
 	"github.com/codeallergy/glue"
 	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"go.uber.org/zap"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 )
 
+var configRepositoryClass = reflect.TypeOf((*sprint.ConfigRepository)(nil)).Elem()
+
 type implRunNode struct {
 	Application                       sprint.Application                       `inject`
 	ApplicationFlags                  sprint.ApplicationFlags                  `inject`
@@ -133,6 +137,8 @@ func (t *implRunNode) Run(args []string) (err error) {
 	}
 	defer logger.Sync()
 
+	t.warnUnknownEnv(core, logger)
+
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("NodeRecover", zap.Error(err))
@@ -186,6 +192,40 @@ func (t *implRunNode) getNodeName() string {
 	return sprintutils.AppendNodeSequence(t.Application.Name(), t.ApplicationFlags.Node())
 }
 
+/**
+	Warns about variables with the application prefix that map to no known property, the keys are taken from schemas and the config repository.
+ */
+
+func (t *implRunNode) warnUnknownEnv(core glue.Context, logger *zap.Logger) {
+
+	inspector, ok := t.SystemEnvironmentPropertyResolver.(sprintapp.EnvironmentInspector)
+	if !ok {
+		return
+	}
+
+	var keys []string
+	for _, bean := range core.Bean(sprintapp.PropertySchemaClass, glue.DefaultLevel) {
+		if schema, ok := bean.Object().(sprintapp.PropertySchema); ok {
+			for _, def := range schema.PropertyDefs() {
+				keys = append(keys, def.Key)
+			}
+		}
+	}
+
+	for _, bean := range core.Bean(configRepositoryClass, glue.DefaultLevel) {
+		if repo, ok := bean.Object().(sprint.ConfigRepository); ok {
+			repo.EnumerateAll("", func(key, value string) bool {
+				keys = append(keys, key)
+				return true
+			})
+		}
+	}
+
+	for _, env := range inspector.UnknownEnviron(keys) {
+		logger.Warn("UnknownEnvironmentVariable", zap.String("env", env))
+	}
+}
+
 func findZapLogger(core glue.Context) (*zap.Logger, bool) {
 	list := core.Bean(sprint.ZapLogClass, glue.DefaultLevel)
 	if len(list) > 0 {
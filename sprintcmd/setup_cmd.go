@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"github.com/codeallergy/glue"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"os/user"
 	"strings"
@@ -24,6 +25,8 @@ type implSetupCommand struct {
 
 type coreSetupContext struct {
 	ConfigRepository sprint.ConfigRepository `inject`
+	NodeService      sprint.NodeService      `inject`
+	Properties       glue.Properties         `inject`
 }
 
 func SetupCommand() sprint.Command {
@@ -61,6 +64,8 @@ func (t *implSetupCommand) Run(args []string) error {
 	t.Properties.Set("application.boot", boot)
 
 	var secretKey []byte
+	var keyring *sprintutils.AuthKeyring
+	var tokenId string
 
 	c := new(coreSetupContext)
 	err = doInCore(t.Context, c, func(core glue.Context) error {
@@ -78,12 +83,15 @@ func (t *implSetupCommand) Run(args []string) error {
 				return err
 			}
 		}
+		tokenId = c.NodeService.Issue().String()
 		secretKey, err = base64.RawURLEncoding.DecodeString(secret)
 		fmt.Printf("JWT secret key %s\n", secretKey)
 		if err != nil {
 			return err
 		}
-		return nil
+		// the active key of the keyring signs if the node was set up before
+		keyring, err = sprintapp.LoadAuthKeyring(c.Properties)
+		return err
 	})
 
 	if err != nil {
@@ -107,7 +115,8 @@ func (t *implSetupCommand) Run(args []string) error {
 		ExpiresAt: time.Now().Unix() + 356*24*3600,
 	}
 
-	auth, err := sprintutils.GenerateAuthToken(secretKey, authUser)
+	policy := sprintapp.AuthTokenPolicy(t.Application.Name(), t.Properties)
+	auth, err := sprintutils.GenerateAuthToken(keyring, tokenId, authUser, policy)
 	if err != nil {
 		return err
 	}
@@ -60,10 +60,14 @@ func (t *implStartNode) Run(args []string) error {
 	}
 
 	/**
-	Prompt all required tokens before start, so we can pass them through to child process environment
+	Prompt all required tokens before start, so we can pass them through to child process environment.
+	Tokens resolved by properties, for example from secret files, the child process resolves the same way.
 	 */
 	for _, token := range t.BootstrapTokens {
-		t.SystemEnvironmentPropertyResolver.PromptProperty(fmt.Sprintf("application.%s", token))
+		key := fmt.Sprintf("application.%s", token)
+		if t.Properties.GetString(key, "") == "" {
+			t.SystemEnvironmentPropertyResolver.PromptProperty(key)
+		}
 	}
 
 	return t.Start(logger, false)
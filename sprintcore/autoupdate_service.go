@@ -12,6 +12,9 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"fmt"
@@ -45,6 +48,23 @@ func AutoupdateService() sprint.AutoupdateService {
 	return &implAutoupdateService{}
 }
 
+func (t *implAutoupdateService) BeanName() string {
+	return "autoupdate_service"
+}
+
+func (t *implAutoupdateService) GetStats(cb func(name, value string) bool) error {
+	jobs := t.FreezeJobs()
+	var names []string
+	for _, name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cb("file", t.AutoupdateFile)
+	cb("pending", strconv.FormatBool(t.triggerAfterUnfreeze.Load() && len(jobs) > 0))
+	cb("freezeJobs", strings.Join(names, ", "))
+	return nil
+}
+
 func (t *implAutoupdateService) PostConstruct() error {
 	autoupdateFile := t.AutoupdateFile
 	if autoupdateFile != "" {
@@ -0,0 +1,281 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore
+
+import (
+	"github.com/codeallergy/glue"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+/**
+	Crash-safe rotation of the encryption key of badger stores.
+
+	Badger encrypts data keys in the key registry file by the storage key, therefore rotation rewrites only this file.
+	Rotation goes in three phases for all stores:
+	  prepare - writes the registry for the new key to KEYREGISTRY.new, current registry stays as is
+	  commit  - moves the current registry to KEYREGISTRY.old and KEYREGISTRY.new to the current
+	  finish  - removes KEYREGISTRY.old
+
+	Until finish either the old or the new key keeps working, RecoverBadgerKeyRotation on the next start selects the registry
+	that opens with the given key. The first start with the new key finishes the rotation, the start with the old key rolls it back.
+ */
+
+var (
+	BadgerKeyRegistryNew = badger.KeyRegistryFileName + ".new"
+	BadgerKeyRegistryOld = badger.KeyRegistryFileName + ".old"
+
+	badgerKeyRotationDuration = 10 * 24 * time.Hour
+)
+
+func PrepareBadgerKeyRotation(dir string, oldKey, newKey []byte) error {
+
+	if !fileExists(filepath.Join(dir, badger.KeyRegistryFileName)) {
+		return errors.Errorf("key registry not found in '%s'", dir)
+	}
+
+	kr, err := badger.OpenKeyRegistry(badger.KeyRegistryOptions{
+		Dir:                           dir,
+		ReadOnly:                      true,
+		EncryptionKey:                 oldKey,
+		EncryptionKeyRotationDuration: badgerKeyRotationDuration,
+	})
+	if err != nil {
+		return errors.Errorf("open key registry in '%s' with the old key, %v", dir, err)
+	}
+
+	tmpDir, err := ioutil.TempDir(dir, "rotate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = badger.WriteKeyRegistry(kr, badger.KeyRegistryOptions{
+		Dir:                           tmpDir,
+		EncryptionKey:                 newKey,
+		EncryptionKeyRotationDuration: badgerKeyRotationDuration,
+	})
+	if err != nil {
+		return errors.Errorf("write key registry for '%s' with the new key, %v", dir, err)
+	}
+
+	return os.Rename(filepath.Join(tmpDir, badger.KeyRegistryFileName), filepath.Join(dir, BadgerKeyRegistryNew))
+}
+
+func CommitBadgerKeyRotation(dir string) error {
+
+	current := filepath.Join(dir, badger.KeyRegistryFileName)
+	newFile := filepath.Join(dir, BadgerKeyRegistryNew)
+
+	if !fileExists(newFile) {
+		return errors.Errorf("prepared key registry not found in '%s'", dir)
+	}
+
+	if err := os.Rename(current, filepath.Join(dir, BadgerKeyRegistryOld)); err != nil {
+		return err
+	}
+	return os.Rename(newFile, current)
+}
+
+func FinishBadgerKeyRotation(dir string) error {
+	err := os.Remove(filepath.Join(dir, BadgerKeyRegistryOld))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+/**
+	Completes or rolls back the interrupted rotation, depending on the key that opens the store.
+	Returns true if there was the interrupted rotation.
+ */
+
+func RecoverBadgerKeyRotation(dir string, key []byte) (bool, error) {
+
+	current := filepath.Join(dir, badger.KeyRegistryFileName)
+	newFile := filepath.Join(dir, BadgerKeyRegistryNew)
+	oldFile := filepath.Join(dir, BadgerKeyRegistryOld)
+
+	hasNew, hasOld := fileExists(newFile), fileExists(oldFile)
+	if !hasNew && !hasOld {
+		return false, nil
+	}
+
+	if canOpenKeyRegistry(current, key) {
+		// the key is the one of the current registry, drop the other side
+		if hasNew {
+			if err := os.Remove(newFile); err != nil {
+				return true, err
+			}
+		}
+		if hasOld {
+			if err := os.Remove(oldFile); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	for _, candidate := range []string{newFile, oldFile} {
+		if fileExists(candidate) && canOpenKeyRegistry(candidate, key) {
+			if fileExists(current) {
+				if err := os.Remove(current); err != nil {
+					return true, err
+				}
+			}
+			if err := os.Rename(candidate, current); err != nil {
+				return true, err
+			}
+			if candidate == newFile && hasOld {
+				return true, os.Remove(oldFile)
+			}
+			if candidate == oldFile && hasNew {
+				return true, os.Remove(newFile)
+			}
+			return true, nil
+		}
+	}
+
+	return true, errors.Errorf("none of key registries in '%s' opens with the given key", dir)
+}
+
+func canOpenKeyRegistry(file string, key []byte) bool {
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+
+	tmpDir, err := ioutil.TempDir(filepath.Dir(file), "check")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, badger.KeyRegistryFileName), data, 0600); err != nil {
+		return false
+	}
+
+	_, err = badger.OpenKeyRegistry(badger.KeyRegistryOptions{
+		Dir:                           tmpDir,
+		ReadOnly:                      true,
+		EncryptionKey:                 key,
+		EncryptionKeyRotationDuration: badgerKeyRotationDuration,
+	})
+	return err == nil
+}
+
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+var EncryptedStoreFactoryClass = reflect.TypeOf((*EncryptedStoreFactory)(nil)).Elem()
+
+/**
+	Factory of the encrypted store that knows the directory of the key registry without opening the store.
+ */
+
+type EncryptedStoreFactory interface {
+
+	ObjectName() string
+
+	KeyDir() string
+
+}
+
+/**
+	Resolves key registry directories of all encrypted stores of the context, opened or not.
+	Fails if the directory of the store has data, but no key registry, because such store can not be re-keyed.
+ */
+
+func EncryptedStoreKeyDirs(ctx glue.Context, registry EncryptedStoreRegistry) (map[string]string, error) {
+
+	dirs := make(map[string]string)
+	if registry != nil {
+		dirs = registry.KeyDirs()
+	}
+
+	for _, bean := range ctx.Bean(EncryptedStoreFactoryClass, glue.DefaultLevel) {
+		factory, ok := bean.Object().(EncryptedStoreFactory)
+		if !ok {
+			continue
+		}
+		name, dir := factory.ObjectName(), factory.KeyDir()
+		if registered, ok := dirs[name]; ok && registered != dir {
+			return nil, errors.Errorf("encrypted store '%s' is registered with '%s', but resolved to '%s'", name, registered, dir)
+		}
+		if hasKeyRegistry(dir) {
+			dirs[name] = dir
+			continue
+		}
+		if list, err := ioutil.ReadDir(dir); err == nil && len(list) > 0 {
+			return nil, errors.Errorf("encrypted store '%s' in '%s' has no key registry", name, dir)
+		}
+		// the store was never created
+		delete(dirs, name)
+	}
+
+	return dirs, nil
+}
+
+func hasKeyRegistry(dir string) bool {
+	for _, name := range []string{badger.KeyRegistryFileName, BadgerKeyRegistryNew, BadgerKeyRegistryOld} {
+		if fileExists(filepath.Join(dir, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+var EncryptedStoreRegistryClass = reflect.TypeOf((*EncryptedStoreRegistry)(nil)).Elem()
+
+/**
+	Keeps directories of key registries of encrypted stores created in the context, uses by the boot token rotation.
+ */
+
+type EncryptedStoreRegistry interface {
+
+	Register(name, keyDir string)
+
+	/**
+	Gets the map of store name to the directory of the key registry.
+	 */
+
+	KeyDirs() map[string]string
+
+}
+
+type implEncryptedStoreRegistry struct {
+	mu    sync.Mutex
+	dirs  map[string]string
+}
+
+func StoreKeyRegistry() EncryptedStoreRegistry {
+	return &implEncryptedStoreRegistry{dirs: make(map[string]string)}
+}
+
+func (t *implEncryptedStoreRegistry) Register(name, keyDir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dirs[name] = keyDir
+}
+
+func (t *implEncryptedStoreRegistry) KeyDirs() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dirs := make(map[string]string)
+	for k, v := range t.dirs {
+		dirs[k] = v
+	}
+	return dirs
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"github.com/dgraph-io/badger/v3"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openBadger(dir string, key []byte) (*badger.DB, error) {
+	return badger.Open(badger.DefaultOptions(dir).
+		WithEncryptionKey(key).
+		WithIndexCacheSize(1 << 20).
+		WithLogger(nil))
+}
+
+func generateStorageKey(t *testing.T) []byte {
+	token, err := sprintutils.GenerateToken()
+	require.NoError(t, err)
+	key, err := sprintutils.ParseToken(token)
+	require.NoError(t, err)
+	return key
+}
+
+func readValue(t *testing.T, db *badger.DB, key string) string {
+	var value []byte
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	require.NoError(t, err)
+	return string(value)
+}
+
+func TestBadgerKeyRotation(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "badger-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldKey, newKey := generateStorageKey(t), generateStorageKey(t)
+
+	db, err := openBadger(dir, oldKey)
+	require.NoError(t, err)
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("name"), []byte("value"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, sprintcore.PrepareBadgerKeyRotation(dir, oldKey, newKey))
+	require.NoError(t, sprintcore.CommitBadgerKeyRotation(dir))
+
+	// both keys work until the first start with the new key
+	require.FileExists(t, filepath.Join(dir, sprintcore.BadgerKeyRegistryOld))
+
+	recovered, err := sprintcore.RecoverBadgerKeyRotation(dir, newKey)
+	require.NoError(t, err)
+	require.True(t, recovered)
+	require.NoFileExists(t, filepath.Join(dir, sprintcore.BadgerKeyRegistryOld))
+
+	db, err = openBadger(dir, newKey)
+	require.NoError(t, err)
+	require.Equal(t, "value", readValue(t, db, "name"))
+	require.NoError(t, db.Close())
+
+	_, err = openBadger(dir, oldKey)
+	require.Error(t, err)
+}
+
+func TestBadgerKeyRotationRollback(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "badger-rollback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldKey, newKey := generateStorageKey(t), generateStorageKey(t)
+
+	db, err := openBadger(dir, oldKey)
+	require.NoError(t, err)
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("name"), []byte("value"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, sprintcore.PrepareBadgerKeyRotation(dir, oldKey, newKey))
+	require.NoError(t, sprintcore.CommitBadgerKeyRotation(dir))
+
+	recovered, err := sprintcore.RecoverBadgerKeyRotation(dir, oldKey)
+	require.NoError(t, err)
+	require.True(t, recovered)
+
+	db, err = openBadger(dir, oldKey)
+	require.NoError(t, err)
+	require.Equal(t, "value", readValue(t, db, "name"))
+	require.NoError(t, db.Close())
+}
@@ -27,6 +27,7 @@ type implBadgerStoreFactory struct {
 	ApplicationFlags                  sprint.ApplicationFlags               `inject`
 	Properties                        glue.Properties                       `inject`
 	SystemEnvironmentPropertyResolver sprint.SystemEnvironmentPropertyResolver `inject`
+	EncryptedStoreRegistry            EncryptedStoreRegistry                `inject:"optional"`
 
 	DataDir           string       `value:"application.data.dir,default="`
 	DataDirPerm       os.FileMode  `value:"application.perm.data.dir,default=-rwxrwx---"`
@@ -50,30 +51,24 @@ func (t *implBadgerStoreFactory) Object() (object interface{}, err error) {
 		}
 	}
 
-	dataDir := t.DataDir
-	if dataDir == "" {
-		dataDir = filepath.Join(t.Application.ApplicationDir(), "db")
+	dataDir, keyDir, splitKeyValueDirs := t.storeDirs()
 
-		if err := sprintutils.CreateDirIfNeeded(dataDir, t.DataDirPerm); err != nil {
+	if t.DataDir == "" {
+		if err := sprintutils.CreateDirIfNeeded(filepath.Dir(filepath.Dir(dataDir)), t.DataDirPerm); err != nil {
 			return nil, err
 		}
-
-		dataDir = filepath.Join(dataDir, t.getNodeName())
 	}
 
-	if err := sprintutils.CreateDirIfNeeded(dataDir, t.DataDirPerm); err != nil {
+	if err := sprintutils.CreateDirIfNeeded(filepath.Dir(dataDir), t.DataDirPerm); err != nil {
 		return nil, err
 	}
 
-	dataDir = filepath.Join(dataDir, t.beanName)
 	if err := sprintutils.CreateDirIfNeeded(dataDir, t.DataDirPerm); err != nil {
 		return nil, err
 	}
 
-	splitKeyValueDirs := t.Properties.GetBool(fmt.Sprintf("%s.split-key-value", t.beanName), false)
 	if splitKeyValueDirs {
-		keyDataDir := filepath.Join(dataDir, "key")
-		if err := sprintutils.CreateDirIfNeeded(keyDataDir, t.DataDirPerm); err != nil {
+		if err := sprintutils.CreateDirIfNeeded(keyDir, t.DataDirPerm); err != nil {
 			return nil, err
 		}
 		valueDataDir := filepath.Join(dataDir, "value")
@@ -94,6 +89,17 @@ func (t *implBadgerStoreFactory) Object() (object interface{}, err error) {
 		dataDirOpt = badgerstore.WithDataDir(dataDir)
 	}
 
+	// complete or roll back the boot token rotation interrupted by crash
+	if recovered, err := RecoverBadgerKeyRotation(keyDir, storageKey); err != nil {
+		return nil, errors.Errorf("recover key rotation of '%s', %v", t.beanName, err)
+	} else if recovered {
+		t.Log.Warn("RecoverKeyRotation", zap.String("bean", t.beanName), zap.String("dir", keyDir))
+	}
+
+	if t.EncryptedStoreRegistry != nil {
+		t.EncryptedStoreRegistry.Register(t.beanName, keyDir)
+	}
+
 	indexCacheSize := t.Properties.GetInt(fmt.Sprintf("%s.index-cache-size", t.beanName), 100 * 1024 * 1024)
 	valueLogMaxEntries := t.Properties.GetInt(fmt.Sprintf("%s.value-log-max-entries", t.beanName), 1024 * 1024 * 1024)
 	openTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.open-timeout", t.beanName), time.Second)
@@ -110,6 +116,31 @@ func (t *implBadgerStoreFactory) Object() (object interface{}, err error) {
 
 }
 
+/**
+	Gets directories of the store without creating them, the key registry is in keyDir.
+ */
+
+func (t *implBadgerStoreFactory) storeDirs() (dataDir, keyDir string, split bool) {
+
+	dataDir = t.DataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(t.Application.ApplicationDir(), "db", t.getNodeName())
+	}
+	dataDir = filepath.Join(dataDir, t.beanName)
+
+	keyDir = dataDir
+	split = t.Properties.GetBool(fmt.Sprintf("%s.split-key-value", t.beanName), false)
+	if split {
+		keyDir = filepath.Join(dataDir, "key")
+	}
+	return
+}
+
+func (t *implBadgerStoreFactory) KeyDir() string {
+	_, keyDir, _ := t.storeDirs()
+	return keyDir
+}
+
 func (t *implBadgerStoreFactory) ObjectType() reflect.Type {
 	return badgerstore.ObjectType()
 }
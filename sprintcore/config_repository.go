@@ -7,18 +7,38 @@ package sprintcore
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/codeallergy/glue"
 	"github.com/keyvalstore/store"
+	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
 	ConfigBucket    = "config"
 	ConfigBucketLen = len(ConfigBucket)
+
+	ConfigHistoryBucket = "config-history"
+)
+
+var ErrConfigVersionNotFound = errors.New("config version not found")
+
+/**
+	Overflow policies of the config watcher queue.
+ */
+
+var (
+	ConfigWatchDropOldest  = "drop-oldest"
+	ConfigWatchCoalesce    = "coalesce"
+	ConfigWatchDisconnect  = "disconnect"
 )
 
 /**
@@ -34,10 +54,28 @@ type implConfigRepository struct {
 	priority int
 
 	Log          *zap.Logger           `inject`
+	Properties   glue.Properties       `inject`
+	Schemas      []sprintapp.PropertySchema  `inject:"optional"`
+	ChangeSources  []sprintapp.PropertyChangeSource  `inject:"optional,level=-1"`
+
+	muSet     sync.Mutex  // serializes changes to keep versions in order
+
+	sourceCancels  []func()
+
+	muKey     sync.Mutex
+	deks      [][]byte    // data keys of secrets, the first one encrypts
+
+	WatchQueueSize  int     `value:"config.watch.queue-size,default=64"`
+	WatchOverflow   string  `value:"config.watch.overflow,default=coalesce"`
 
 	watchNum  atomic.Int64
 	watchMap  sync.Map       // watchNum, configWatchContext
 
+	watchActive        atomic.Int64
+	watchDelivered     atomic.Int64
+	watchDropped       atomic.Int64
+	watchDisconnected  atomic.Int64
+
 	shuttingDown  atomic.Bool
 }
 
@@ -50,7 +88,53 @@ type configWatchContext struct {
 	ctx       context.Context
 	cancelFn  context.CancelFunc
 	prefix    string
-	ch        chan<- configEntryChange
+
+	mu        sync.Mutex
+	queue     []configEntryChange
+	signal    chan struct{}  // buffered by one, wakes up the watcher goroutine
+}
+
+/**
+	Puts the change to the queue without blocking the caller, applies overflow policy if the queue is full.
+	Returns false if the watcher has to be disconnected.
+ */
+
+func (wc *configWatchContext) offer(e configEntryChange, queueSize int, overflow string) (dropped, ok bool) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if overflow == ConfigWatchCoalesce {
+		for i, pending := range wc.queue {
+			if pending.key == e.key {
+				wc.queue[i] = e
+				return false, true
+			}
+		}
+	}
+
+	if len(wc.queue) >= queueSize {
+		if overflow == ConfigWatchDisconnect {
+			return true, false
+		}
+		wc.queue = wc.queue[1:]
+		dropped = true
+	}
+
+	wc.queue = append(wc.queue, e)
+
+	select {
+	case wc.signal <- struct{}{}:
+	default:
+	}
+	return dropped, true
+}
+
+func (wc *configWatchContext) drain() []configEntryChange {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	list := wc.queue
+	wc.queue = nil
+	return list
 }
 
 func ConfigRepository(priority int) sprint.ConfigRepository {
@@ -63,6 +147,31 @@ func (t *implConfigRepository) String() string {
 	return fmt.Sprintf("ConfigRepository{%d}", t.priority)
 }
 
+func (t *implConfigRepository) BeanName() string {
+	return "config_repository"
+}
+
+func (t *implConfigRepository) GetStats(cb func(name, value string) bool) error {
+	cb("watchers", strconv.FormatInt(t.watchActive.Load(), 10))
+	cb("watchQueueSize", strconv.Itoa(t.queueSize()))
+	cb("watchOverflow", t.WatchOverflow)
+	cb("watchDelivered", strconv.FormatInt(t.watchDelivered.Load(), 10))
+	cb("watchDropped", strconv.FormatInt(t.watchDropped.Load(), 10))
+	cb("watchDisconnected", strconv.FormatInt(t.watchDisconnected.Load(), 10))
+	return nil
+}
+
+func (t *implConfigRepository) queueSize() int {
+	if t.WatchQueueSize <= 0 {
+		return 1
+	}
+	return t.WatchQueueSize
+}
+
+func (t *implConfigRepository) SourceName() string {
+	return "store"
+}
+
 func (t *implConfigRepository) Priority() int {
 	return t.priority
 }
@@ -78,8 +187,52 @@ func (t *implConfigRepository) GetProperty(key string) (value string, ok bool) {
 	return value, true
 }
 
+/**
+	Changes of runtime property sources like secret files go to watchers of the repository,
+	unless the entry is in the store that has the higher priority.
+ */
+
+func (t *implConfigRepository) PostConstruct() error {
+	switch t.WatchOverflow {
+	case ConfigWatchDropOldest, ConfigWatchCoalesce, ConfigWatchDisconnect:
+	default:
+		t.Log.Warn("ConfigWatchOverflow", zap.String("unknown", t.WatchOverflow), zap.String("policy", ConfigWatchCoalesce))
+		t.WatchOverflow = ConfigWatchCoalesce
+	}
+	for _, source := range t.ChangeSources {
+		cancel, err := source.SubscribeChanges(t.onSourceChange)
+		if err != nil {
+			t.Log.Warn("ConfigSourceWatch", zap.Any("source", source), zap.Error(err))
+			continue
+		}
+		t.sourceCancels = append(t.sourceCancels, cancel)
+	}
+	if cnt, err := t.EncryptPlainSecrets(); err != nil {
+		t.Log.Warn("ConfigSecretMigration", zap.Error(err))
+	} else if cnt > 0 {
+		t.Log.Info("ConfigSecretMigration", zap.Int("encrypted", cnt))
+	}
+	return nil
+}
+
+func (t *implConfigRepository) onSourceChange(key, value string) {
+	if t.shuttingDown.Load() {
+		return
+	}
+	t.muSet.Lock()
+	defer t.muSet.Unlock()
+	if stored, err := t.getStored(key); err == nil && stored != "" {
+		return
+	}
+	t.Log.Info("ConfigSourceChange", zap.String("key", key), zap.Bool("emptyValue", value == ""))
+	t.notifyAll(configEntryChange{key: key, value: value})
+}
+
 func (t *implConfigRepository) Destroy() error {
 	t.shuttingDown.Store(true)
+	for _, cancel := range t.sourceCancels {
+		cancel()
+	}
 	if t.watchNum.Load() > 0 {
 		t.watchMap.Range(func(key, value interface{}) bool {
 			if wc, ok := value.(*configWatchContext); ok {
@@ -91,12 +244,20 @@ func (t *implConfigRepository) Destroy() error {
 	return nil
 }
 
+/**
+	Gets the value of the config entry, secrets are decrypted.
+ */
+
 func (t *implConfigRepository) Get(key string) (string, error) {
-	value, err := t.Backend().Get(context.Background()).ByKey("%s:%s", ConfigBucket, key).ToString()
+	value, err := t.getStored(key)
 	if err != nil {
 		return "", err
 	}
-	return value, nil
+	return t.decrypt(key, value)
+}
+
+func (t *implConfigRepository) getStored(key string) (string, error) {
+	return t.Backend().Get(context.Background()).ByKey("%s:%s", ConfigBucket, key).ToString()
 }
 
 func (t *implConfigRepository) EnumerateAll(prefix string, cb func(key, value string) bool) error {
@@ -111,16 +272,175 @@ func (t *implConfigRepository) EnumerateAll(prefix string, cb func(key, value st
 }
 
 func (t *implConfigRepository) Set(key, value string) error {
-	err := t.doSet(key, value)
-	if err != nil {
+	return t.SetAs(key, value, "")
+}
+
+func (t *implConfigRepository) SetAs(key, value, username string) error {
+	if err := t.Validate(key, value); err != nil {
+		return err
+	}
+	t.muSet.Lock()
+	defer t.muSet.Unlock()
+	if _, err := t.setWithHistory(key, value, username); err != nil {
 		return err
 	}
 	if t.watchNum.Load() != 0 {
+		// enqueue under the lock to deliver changes in the order of versions, offer never blocks
+		// watchers get the plaintext in the same way as from the runtime sources
 		t.notifyAll(configEntryChange{key, value})
 	}
 	return nil
 }
 
+/**
+	Validates the value against the registered schema, keys without schema accept any value.
+ */
+
+func (t *implConfigRepository) Validate(key, value string) error {
+	if def, ok := t.DescribeProperty(key); ok {
+		if err := def.Validate(value); err != nil {
+			return errors.Errorf("config entry '%s', %v", key, err)
+		}
+	}
+	return nil
+}
+
+func (t *implConfigRepository) DescribeProperty(key string) (*sprintapp.PropertyDef, bool) {
+	return sprintapp.FindPropertyDef(t.Schemas, key)
+}
+
+/**
+	Stores the value and records the change in history, secrets are encrypted in both places.
+	Returns the stored value, the caller holds muSet.
+ */
+
+func (t *implConfigRepository) setWithHistory(key, value, username string) (string, error) {
+
+	stored, err := t.encrypt(key, value)
+	if err != nil {
+		return "", err
+	}
+
+	oldStored, err := t.getStored(key)
+	if err != nil {
+		return "", err
+	}
+
+	oldValue, err := t.decrypt(key, oldStored)
+	if err != nil {
+		return "", err
+	}
+
+	newValue, err := t.decrypt(key, stored)
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.doSet(key, stored); err != nil {
+		return "", err
+	}
+
+	if oldValue == newValue {
+		return stored, nil
+	}
+
+	history, err := t.History(key)
+	if err != nil {
+		return "", err
+	}
+
+	var version int64 = 1
+	if len(history) > 0 {
+		version = history[len(history)-1].Version + 1
+	}
+
+	change := &sprintapp.ConfigChange{
+		Version:   version,
+		Timestamp: time.Now().UnixMilli(),
+		User:      username,
+		OldValue:  oldStored,
+		NewValue:  stored,
+	}
+
+	return stored, t.putHistory(key, change)
+}
+
+/**
+	History records are keyed by 'config-history:<key>:<version>', version is the last segment of digits only.
+ */
+
+func (t *implConfigRepository) putHistory(key string, change *sprintapp.ConfigChange) error {
+	record, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return t.Backend().Set(context.Background()).ByKey("%s:%s:%010d", ConfigHistoryBucket, key, change.Version).Binary(record)
+}
+
+func (t *implConfigRepository) History(key string) ([]*sprintapp.ConfigChange, error) {
+
+	var list []*sprintapp.ConfigChange
+	var lastErr error
+
+	prefixLen := len(ConfigHistoryBucket) + len(key) + 2
+
+	err := t.Backend().
+		Enumerate(context.Background()).
+		ByPrefix("%s:%s:", ConfigHistoryBucket, key).
+		WithBatchSize(256).
+		Do(func(entry *store.RawEntry) bool {
+			// the prefix of key 'a' matches records of key 'a:b' as well, their suffix is not a plain version
+			if !isDigits(entry.Key[prefixLen:]) {
+				return true
+			}
+			change := new(sprintapp.ConfigChange)
+			if err := json.Unmarshal(entry.Value, change); err != nil {
+				lastErr = errors.Errorf("invalid config history record '%s', %v", string(entry.Key), err)
+				return false
+			}
+			list = append(list, change)
+			return true
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, lastErr
+}
+
+func isDigits(suffix []byte) bool {
+	if len(suffix) == 0 {
+		return false
+	}
+	for _, ch := range suffix {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *implConfigRepository) Rollback(key string, version int64, username string) error {
+
+	history, err := t.History(key)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range history {
+		if change.Version == version {
+			value, err := t.decrypt(key, change.NewValue)
+			if err != nil {
+				return err
+			}
+			return t.SetAs(key, value, username)
+		}
+	}
+
+	return ErrConfigVersionNotFound
+}
+
 func (t *implConfigRepository) doSet(key, value string) error {
 	if value == "" {
 		return t.Backend().Remove(context.Background()).ByKey("%s:%s", ConfigBucket, key).Do()
@@ -130,10 +450,19 @@ func (t *implConfigRepository) doSet(key, value string) error {
 }
 
 func (t *implConfigRepository) notifyAll(e configEntryChange) {
+	queueSize := t.queueSize()
 	t.watchMap.Range(func(key, value interface{}) bool {
 		if wc, ok := value.(*configWatchContext); ok {
 			if strings.HasPrefix(e.key, wc.prefix) {
-				wc.ch <- e
+				dropped, ok := wc.offer(e, queueSize, t.WatchOverflow)
+				if dropped {
+					t.watchDropped.Inc()
+				}
+				if !ok {
+					t.watchDisconnected.Inc()
+					t.Log.Warn("ConfigWatcherDisconnected", zap.String("prefix", wc.prefix), zap.Int("queueSize", queueSize))
+					wc.cancelFn()
+				}
 			}
 		}
 		return true
@@ -152,18 +481,34 @@ func (t *implConfigRepository) unregisterWatch(handle int64) {
 
 // use Application as ctx
 func (t *implConfigRepository) Watch(ctx context.Context, prefix string, cb func(key, value string) bool) (cancel context.CancelFunc, err error) {
+	cancel, _ = t.watch(ctx, prefix, cb)
+	return
+}
+
+/**
+	Watches changes until the context is done or the watcher gets disconnected.
+	Returned channel closes when the watcher stops.
+ */
+
+func (t *implConfigRepository) WatchUntilDone(ctx context.Context, prefix string, cb func(key, value string) bool) (<-chan struct{}, error) {
+	_, done := t.watch(ctx, prefix, cb)
+	return done, nil
+}
+
+func (t *implConfigRepository) watch(ctx context.Context, prefix string, cb func(key, value string) bool) (cancel context.CancelFunc, done chan struct{}) {
 
 	ctx, cancel = context.WithCancel(ctx)
-	ch := make(chan configEntryChange)
+	done = make(chan struct{})
 
 	wc := &configWatchContext{
 		ctx: ctx,
 		cancelFn: cancel,
 		prefix: prefix,
-		ch: ch,
+		signal: make(chan struct{}, 1),
 	}
 
 	handle := t.registerWatch(wc)
+	t.watchActive.Inc()
 
 	go func() {
 
@@ -182,7 +527,9 @@ func (t *implConfigRepository) Watch(ctx context.Context, prefix string, cb func
 
 		defer func() {
 			t.unregisterWatch(handle)
-			close(ch)
+			t.watchActive.Dec()
+			cancel()
+			close(done)
 		}()
 
 		for {
@@ -191,9 +538,12 @@ func (t *implConfigRepository) Watch(ctx context.Context, prefix string, cb func
 			case <- ctx.Done():
 				return
 
-			case e := <- ch:
-				if !cb(e.key, e.value) {
-					return
+			case <- wc.signal:
+				for _, e := range wc.drain() {
+					t.watchDelivered.Inc()
+					if !cb(e.key, e.value) {
+						return
+					}
 				}
 
 			}
@@ -0,0 +1,426 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type versionedConfigRepository interface {
+	sprint.ConfigRepository
+	sprintapp.VersionedConfigRepository
+}
+
+type configRepositoryHolder struct {
+	Repository sprint.ConfigRepository `inject`
+}
+
+func newConfigRepository(t *testing.T, beans ...interface{}) (versionedConfigRepository, func()) {
+
+	holder := new(configRepositoryHolder)
+	_, done := newStoreContext(t, append(beans, zap.NewNop(), sprintcore.ConfigRepository(100), holder)...)
+
+	repo, ok := holder.Repository.(versionedConfigRepository)
+	require.True(t, ok)
+
+	return repo, done
+}
+
+func TestConfigHistoryRollback(t *testing.T) {
+
+	repo, done := newConfigRepository(t)
+	defer done()
+
+	require.NoError(t, repo.SetAs("a", "1", "alice"))
+	require.NoError(t, repo.SetAs("a", "2", "bob"))
+
+	// the key with the prefix of 'a' and digits in the next segment
+	require.NoError(t, repo.SetAs("a:0000000001", "x", "alice"))
+	require.NoError(t, repo.SetAs("a:b", "y", "alice"))
+	require.NoError(t, repo.SetAs("a:b", "z", "alice"))
+	require.NoError(t, repo.SetAs("a:b", "w", "alice"))
+
+	history, err := repo.History("a")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+	require.Equal(t, int64(1), history[0].Version)
+	require.Equal(t, "", history[0].OldValue)
+	require.Equal(t, "1", history[0].NewValue)
+	require.Equal(t, "alice", history[0].User)
+	require.Equal(t, int64(2), history[1].Version)
+	require.Equal(t, "1", history[1].OldValue)
+	require.Equal(t, "2", history[1].NewValue)
+	require.Equal(t, "bob", history[1].User)
+
+	history, err = repo.History("a:b")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+
+	history, err = repo.History("a:0000000001")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(history))
+
+	require.NoError(t, repo.Rollback("a", 1, "carol"))
+
+	value, err := repo.Get("a")
+	require.NoError(t, err)
+	require.Equal(t, "1", value)
+
+	history, err = repo.History("a")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+	require.Equal(t, int64(3), history[2].Version)
+	require.Equal(t, "2", history[2].OldValue)
+	require.Equal(t, "1", history[2].NewValue)
+	require.Equal(t, "carol", history[2].User)
+
+	// unchanged value does not make the new version
+	require.NoError(t, repo.Rollback("a", 3, "carol"))
+	history, err = repo.History("a")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+
+	require.Equal(t, sprintcore.ErrConfigVersionNotFound, repo.Rollback("a", 7, "carol"))
+
+	value, err = repo.Get("a:b")
+	require.NoError(t, err)
+	require.Equal(t, "w", value)
+}
+
+type configChange struct {
+	key, value string
+}
+
+func receiveChange(t *testing.T, ch <-chan configChange) configChange {
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "config change was not delivered")
+		return configChange{}
+	}
+}
+
+func getStat(t *testing.T, repo interface{}, name string) string {
+	stats, ok := repo.(interface{ GetStats(cb func(name, value string) bool) error })
+	require.True(t, ok)
+	var result string
+	require.NoError(t, stats.GetStats(func(n, value string) bool {
+		if n == name {
+			result = value
+		}
+		return true
+	}))
+	return result
+}
+
+func TestConfigWatch(t *testing.T) {
+
+	repo, done := newConfigRepository(t)
+	defer done()
+
+	ch := make(chan configChange, 16)
+	cancel, err := repo.Watch(context.Background(), "mail.", func(key, value string) bool {
+		ch <- configChange{key, value}
+		return true
+	})
+	require.NoError(t, err)
+	defer cancel()
+
+	require.NoError(t, repo.Set("mail.host", "smtp"))
+	require.NoError(t, repo.Set("web.host", "www"))
+	require.NoError(t, repo.Set("mail.smtp.password", "qwerty"))
+	require.NoError(t, repo.Set("mail.host", ""))
+
+	require.Equal(t, configChange{"mail.host", "smtp"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"mail.smtp.password", "qwerty"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"mail.host", ""}, receiveChange(t, ch))
+}
+
+/**
+	Blocks the watcher on the first change, so next changes stay in the queue until the release.
+ */
+
+func blockedWatch(t *testing.T, repo versionedConfigRepository, prefix string) (<-chan configChange, chan struct{}, context.CancelFunc) {
+
+	ch := make(chan configChange, 16)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := true
+
+	cancel, err := repo.Watch(context.Background(), prefix, func(key, value string) bool {
+		if first {
+			first = false
+			close(started)
+			<-release
+		}
+		ch <- configChange{key, value}
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Set(prefix + "first", "1"))
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "config change was not delivered")
+	}
+
+	return ch, release, cancel
+}
+
+func TestConfigWatchCoalesce(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{
+		"config.watch.queue-size": "2",
+		"config.watch.overflow":   "coalesce",
+	}}
+
+	repo, done := newConfigRepository(t, resolver)
+	defer done()
+
+	ch, release, cancel := blockedWatch(t, repo, "app.")
+	defer cancel()
+
+	for _, value := range []string{"a", "b", "c"} {
+		require.NoError(t, repo.Set("app.mode", value))
+	}
+	require.NoError(t, repo.Set("app.level", "debug"))
+	close(release)
+
+	require.Equal(t, configChange{"app.first", "1"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"app.mode", "c"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"app.level", "debug"}, receiveChange(t, ch))
+	require.Equal(t, "0", getStat(t, repo, "watchDropped"))
+}
+
+func TestConfigWatchDropOldest(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{
+		"config.watch.queue-size": "2",
+		"config.watch.overflow":   "drop-oldest",
+	}}
+
+	repo, done := newConfigRepository(t, resolver)
+	defer done()
+
+	ch, release, cancel := blockedWatch(t, repo, "app.")
+	defer cancel()
+
+	require.NoError(t, repo.Set("app.a", "1"))
+	require.NoError(t, repo.Set("app.b", "2"))
+	require.NoError(t, repo.Set("app.c", "3"))
+	close(release)
+
+	require.Equal(t, configChange{"app.first", "1"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"app.b", "2"}, receiveChange(t, ch))
+	require.Equal(t, configChange{"app.c", "3"}, receiveChange(t, ch))
+	require.Equal(t, "1", getStat(t, repo, "watchDropped"))
+}
+
+func TestConfigWatchDisconnect(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{
+		"config.watch.queue-size": "1",
+		"config.watch.overflow":   "disconnect",
+	}}
+
+	repo, done := newConfigRepository(t, resolver)
+	defer done()
+
+	ch, release, cancel := blockedWatch(t, repo, "app.")
+	defer cancel()
+
+	require.NoError(t, repo.Set("app.a", "1"))
+	require.NoError(t, repo.Set("app.b", "2"))
+	require.Equal(t, "1", getStat(t, repo, "watchDisconnected"))
+	close(release)
+
+	require.Equal(t, configChange{"app.first", "1"}, receiveChange(t, ch))
+
+	// the watcher stops after the current change
+	deadline := time.Now().Add(5 * time.Second)
+	for getStat(t, repo, "watchers") != "0" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, "0", getStat(t, repo, "watchers"))
+}
+
+func TestConfigWatchOrder(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{
+		"config.watch.queue-size": "1000",
+		"config.watch.overflow":   "drop-oldest",
+	}}
+
+	repo, done := newConfigRepository(t, resolver)
+	defer done()
+
+	ch := make(chan configChange, 1000)
+	cancel, err := repo.Watch(context.Background(), "app.", func(key, value string) bool {
+		ch <- configChange{key, value}
+		return true
+	})
+	require.NoError(t, err)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				repo.Set("app.mode", fmt.Sprintf("%d-%d", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	history, err := repo.History("app.mode")
+	require.NoError(t, err)
+
+	// watcher gets changes in the order of versions
+	for _, change := range history {
+		require.Equal(t, configChange{"app.mode", change.NewValue}, receiveChange(t, ch))
+	}
+}
+
+func TestConfigWatchUnknownOverflow(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{
+		"config.watch.overflow": "drop-newest",
+	}}
+
+	repo, done := newConfigRepository(t, resolver)
+	defer done()
+
+	require.Equal(t, "coalesce", getStat(t, repo, "watchOverflow"))
+}
+
+func TestConfigWatchStats(t *testing.T) {
+
+	repo, done := newConfigRepository(t)
+	defer done()
+
+	require.Equal(t, "64", getStat(t, repo, "watchQueueSize"))
+	require.Equal(t, "0", getStat(t, repo, "watchers"))
+
+	ch := make(chan configChange, 16)
+	cancel, err := repo.Watch(context.Background(), "app.", func(key, value string) bool {
+		ch <- configChange{key, value}
+		return true
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "1", getStat(t, repo, "watchers"))
+
+	require.NoError(t, repo.Set("app.a", "1"))
+	require.NoError(t, repo.Set("web.a", "1"))
+	require.NoError(t, repo.Set("app.b", "2"))
+	receiveChange(t, ch)
+	receiveChange(t, ch)
+
+	require.Equal(t, "2", getStat(t, repo, "watchDelivered"))
+	require.Equal(t, "0", getStat(t, repo, "watchDropped"))
+	require.Equal(t, "0", getStat(t, repo, "watchDisconnected"))
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for getStat(t, repo, "watchers") != "0" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, "0", getStat(t, repo, "watchers"))
+}
+
+/**
+	Runtime property source that changes values by the test.
+ */
+
+type testChangeSource struct {
+	mu   sync.Mutex
+	cb   func(key, value string)
+}
+
+func (t *testChangeSource) SubscribeChanges(cb func(key, value string)) (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cb = cb
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.cb = nil
+	}, nil
+}
+
+func (t *testChangeSource) change(key, value string) {
+	t.mu.Lock()
+	cb := t.cb
+	t.mu.Unlock()
+	if cb != nil {
+		cb(key, value)
+	}
+}
+
+func TestConfigSourceChange(t *testing.T) {
+
+	source := new(testChangeSource)
+	repo, done := newConfigRepository(t, source)
+	defer done()
+
+	ch := make(chan configChange, 16)
+	cancel, err := repo.Watch(context.Background(), "application.", func(key, value string) bool {
+		ch <- configChange{key, value}
+		return true
+	})
+	require.NoError(t, err)
+	defer cancel()
+
+	// the store has the higher priority than the source
+	require.NoError(t, repo.Set("application.auth", "stored"))
+	require.Equal(t, configChange{"application.auth", "stored"}, receiveChange(t, ch))
+	source.change("application.auth", "secret")
+
+	source.change("application.boot", "secret")
+	require.Equal(t, configChange{"application.boot", "secret"}, receiveChange(t, ch))
+
+	source.change("application.boot", "")
+	require.Equal(t, configChange{"application.boot", ""}, receiveChange(t, ch))
+	require.Equal(t, "3", getStat(t, repo, "watchDelivered"))
+}
+
+func TestAuthKeyringHistory(t *testing.T) {
+
+	repo, done := newConfigRepository(t)
+	defer done()
+
+	first, err := sprintapp.RotateAuthKey(repo, "", "alice")
+	require.NoError(t, err)
+
+	_, err = sprintapp.RotateAuthKey(repo, "", "alice")
+	require.NoError(t, err)
+
+	require.NoError(t, sprintapp.RetireAuthKey(repo, first, "bob"))
+
+	history, err := repo.History(sprintapp.JwtKeyringActive)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+	require.Equal(t, "alice", history[1].User)
+
+	history, err = repo.History(sprintapp.JwtKeyringKids)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+	require.Equal(t, "alice", history[0].User)
+	require.Equal(t, "bob", history[2].User)
+}
@@ -0,0 +1,449 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/keyvalstore/store"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"go.uber.org/zap"
+)
+
+/**
+	Envelope encryption of the password properties in config.
+	Values are encrypted by the random data key, the data key is stored encrypted by the key derived from 'application.boot' token.
+
+	During the rotation of the boot token the rotation keyring keeps data keys wrapped by both tokens,
+	therefore either the old or the new token works if the process dies midway.
+	The keyring is removed on the first start with the new token.
+ */
+
+var (
+	SecretBucket       = "secret"
+	SecretDataKey      = "config-data-key"
+	SecretRotationKey  = "config-data-key.rotation"
+	SecretKeyPurpose   = "sprint config secrets"
+
+	ErrBootTokenRequired = errors.New("'application.boot' bootstrap token is required to encrypt secrets")
+	ErrWrongBootToken    = errors.New("config data key does not open with 'application.boot' token")
+	ErrInvalidDataKey    = errors.New("invalid config data key")
+)
+
+func (t *implConfigRepository) bootToken() string {
+	if t.Properties == nil {
+		return ""
+	}
+	return t.Properties.GetString("application.boot", "")
+}
+
+/**
+	Gets data keys, the first one encrypts new values, others only decrypt. Generates the data key on first use.
+	Returns empty list if boot token is not available, secrets are stored as is in this case.
+ */
+
+func (t *implConfigRepository) dataKeys() ([][]byte, error) {
+	t.muKey.Lock()
+	defer t.muKey.Unlock()
+
+	if t.deks != nil {
+		return t.deks, nil
+	}
+
+	token := t.bootToken()
+	if token == "" {
+		return nil, nil
+	}
+
+	deks, err := t.loadDataKeys(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deks) == 0 {
+		dek, err := sprintutils.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.storeWrapped(SecretDataKey, token, dek); err != nil {
+			return nil, err
+		}
+		deks = [][]byte{dek}
+	}
+
+	t.deks = deks
+	return deks, nil
+}
+
+func (t *implConfigRepository) loadDataKeys(token string) ([][]byte, error) {
+
+	kek, err := sprintutils.DeriveKey(token, SecretKeyPurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := t.Backend().Get(context.Background()).ByKey("%s:%s", SecretBucket, SecretDataKey).ToString()
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := t.Backend().Get(context.Background()).ByKey("%s:%s", SecretBucket, SecretRotationKey).ToString()
+	if err != nil {
+		return nil, err
+	}
+
+	var rotation []string
+	if keyring != "" {
+		if err := json.Unmarshal([]byte(keyring), &rotation); err != nil {
+			return nil, errors.Errorf("invalid config data key rotation keyring, %v", err)
+		}
+	}
+
+	if wrapped == "" && len(rotation) == 0 {
+		return nil, nil
+	}
+
+	var deks [][]byte
+	primary, err := unwrapDataKey(kek, wrapped)
+	if err == nil {
+		deks = append(deks, primary)
+	}
+
+	var next []byte
+	for _, w := range rotation {
+		if dek, err := unwrapDataKey(kek, w); err == nil {
+			if next == nil {
+				next = dek
+			}
+			deks = append(deks, dek)
+		}
+	}
+
+	if len(deks) == 0 {
+		return nil, ErrWrongBootToken
+	}
+
+	if primary != nil && next != nil && bytes.Equal(primary, next) {
+		// the new data key is stored under the new token, rotation is complete
+		if err := t.Backend().Remove(context.Background()).ByKey("%s:%s", SecretBucket, SecretRotationKey).Do(); err != nil {
+			return nil, err
+		}
+		if t.Log != nil {
+			t.Log.Info("ConfigSecretRotationComplete")
+		}
+	}
+
+	return deks, nil
+}
+
+func unwrapDataKey(kek []byte, wrapped string) ([]byte, error) {
+	if wrapped == "" {
+		return nil, ErrInvalidDataKey
+	}
+	encoded, err := sprintutils.DecryptValue(kek, wrapped, SecretDataKey)
+	if err != nil {
+		return nil, err
+	}
+	return sprintutils.Encoding.DecodeString(encoded)
+}
+
+func wrapDataKey(token string, dek []byte) (string, error) {
+	kek, err := sprintutils.DeriveKey(token, SecretKeyPurpose)
+	if err != nil {
+		return "", err
+	}
+	return sprintutils.EncryptValue(kek, sprintutils.Encoding.EncodeToString(dek), SecretDataKey)
+}
+
+func (t *implConfigRepository) storeWrapped(name, token string, dek []byte) error {
+	wrapped, err := wrapDataKey(token, dek)
+	if err != nil {
+		return err
+	}
+	return t.Backend().Set(context.Background()).ByKey("%s:%s", SecretBucket, name).String(wrapped)
+}
+
+/**
+	Encrypts the value of the password property before it gets stored.
+	Already encrypted value is accepted only if it belongs to this config.
+ */
+
+func (t *implConfigRepository) encrypt(key, value string) (string, error) {
+	if value == "" || !sprintapp.IsPasswordProperty(key) {
+		return value, nil
+	}
+
+	deks, err := t.dataKeys()
+	if err != nil {
+		return "", err
+	}
+
+	if sprintutils.IsEncryptedValue(value) {
+		if len(deks) == 0 {
+			return "", ErrBootTokenRequired
+		}
+		if _, err := decryptWithAny(deks, value, key); err != nil {
+			return "", errors.Errorf("encrypted value of '%s' does not belong to this config, %v", key, err)
+		}
+		return value, nil
+	}
+
+	if len(deks) == 0 {
+		t.warnPlainSecret(key)
+		return value, nil
+	}
+
+	return sprintutils.EncryptValue(deks[0], value, key)
+}
+
+func (t *implConfigRepository) decrypt(key, value string) (string, error) {
+	if !sprintutils.IsEncryptedValue(value) {
+		return value, nil
+	}
+
+	deks, err := t.dataKeys()
+	if err != nil {
+		return "", err
+	}
+	if len(deks) == 0 {
+		return "", ErrBootTokenRequired
+	}
+
+	return decryptWithAny(deks, value, key)
+}
+
+func decryptWithAny(deks [][]byte, value, key string) (plaintext string, err error) {
+	for _, dek := range deks {
+		if plaintext, err = sprintutils.DecryptValue(dek, value, key); err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (t *implConfigRepository) warnPlainSecret(key string) {
+	if t.Log != nil {
+		t.Log.Warn("ConfigSecretNotEncrypted", zap.String("key", key), zap.Error(ErrBootTokenRequired))
+	}
+}
+
+/**
+	Re-encrypts all secrets with the new data key, that is stored encrypted by the key derived from the new boot token.
+	Until the first start with the new token the old token keeps working as well.
+	Returns the number of re-encrypted secrets.
+ */
+
+func (t *implConfigRepository) RotateSecretKey(newBootToken string) (int, error) {
+
+	if newBootToken == "" {
+		return 0, ErrBootTokenRequired
+	}
+
+	oldToken := t.bootToken()
+	if oldToken == "" {
+		return 0, ErrBootTokenRequired
+	}
+
+	t.muSet.Lock()
+	defer t.muSet.Unlock()
+
+	oldKeys, err := t.dataKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	newKey, err := sprintutils.GenerateKey()
+	if err != nil {
+		return 0, err
+	}
+
+	// both tokens open both keys until the rotation is complete, the new key goes first
+	var rotation []string
+	for _, token := range []string{oldToken, newBootToken} {
+		for _, dek := range append([][]byte{newKey}, oldKeys...) {
+			wrapped, err := wrapDataKey(token, dek)
+			if err != nil {
+				return 0, err
+			}
+			rotation = append(rotation, wrapped)
+		}
+	}
+
+	keyring, err := json.Marshal(rotation)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.Backend().Set(context.Background()).ByKey("%s:%s", SecretBucket, SecretRotationKey).Binary(keyring); err != nil {
+		return 0, err
+	}
+
+	secrets := make(map[string]string)
+	err = t.Backend().
+		Enumerate(context.Background()).
+		ByPrefix("%s:", ConfigBucket).
+		WithBatchSize(256).
+		Do(func(entry *store.RawEntry) bool {
+			key := string(entry.Key[ConfigBucketLen+1:])
+			if sprintapp.IsPasswordProperty(key) {
+				secrets[key] = string(entry.Value)
+			}
+			return true
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	reencrypt := func(key, value string) (string, error) {
+		if value == "" {
+			return value, nil
+		}
+		if sprintutils.IsEncryptedValue(value) {
+			if value, err = decryptWithAny(oldKeys, value, key); err != nil {
+				return "", errors.Errorf("decrypt config entry '%s', %v", key, err)
+			}
+		}
+		return sprintutils.EncryptValue(newKey, value, key)
+	}
+
+	for key, value := range secrets {
+		value, err := reencrypt(key, value)
+		if err != nil {
+			return 0, err
+		}
+		if err := t.doSet(key, value); err != nil {
+			return 0, errors.Errorf("store config entry '%s', %v", key, err)
+		}
+		if err := t.reencryptHistory(key, reencrypt); err != nil {
+			return 0, errors.Errorf("re-encrypt history of config entry '%s', %v", key, err)
+		}
+	}
+
+	t.muKey.Lock()
+	defer t.muKey.Unlock()
+
+	if err := t.storeWrapped(SecretDataKey, newBootToken, newKey); err != nil {
+		return 0, err
+	}
+	t.deks = append([][]byte{newKey}, oldKeys...)
+
+	return len(secrets), nil
+}
+
+func (t *implConfigRepository) reencryptHistory(key string, reencrypt func(key, value string) (string, error)) error {
+
+	history, err := t.History(key)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range history {
+		if change.OldValue, err = reencrypt(key, change.OldValue); err != nil {
+			return err
+		}
+		if change.NewValue, err = reencrypt(key, change.NewValue); err != nil {
+			return err
+		}
+		if err := t.putHistory(key, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+	Encrypts password properties stored as is, like the values stored before the boot token was set or before the key became a password property.
+	History of these entries is encrypted as well. Does nothing without the boot token.
+	Returns the number of encrypted entries.
+ */
+
+func (t *implConfigRepository) EncryptPlainSecrets() (int, error) {
+
+	if t.Backend() == nil {
+		return 0, nil
+	}
+
+	deks, err := t.dataKeys()
+	if err != nil || len(deks) == 0 {
+		return 0, err
+	}
+
+	t.muSet.Lock()
+	defer t.muSet.Unlock()
+
+	secrets := make(map[string]string)
+	err = t.Backend().
+		Enumerate(context.Background()).
+		ByPrefix("%s:", ConfigBucket).
+		WithBatchSize(256).
+		Do(func(entry *store.RawEntry) bool {
+			key := string(entry.Key[ConfigBucketLen+1:])
+			if sprintapp.IsPasswordProperty(key) {
+				secrets[key] = string(entry.Value)
+			}
+			return true
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	encryptPlain := func(key, value string) (string, error) {
+		if value == "" || sprintutils.IsEncryptedValue(value) {
+			return value, nil
+		}
+		return sprintutils.EncryptValue(deks[0], value, key)
+	}
+
+	cnt := 0
+	for key, value := range secrets {
+		if !sprintutils.IsEncryptedValue(value) {
+			if value, err = encryptPlain(key, value); err != nil {
+				return cnt, err
+			}
+			if err := t.doSet(key, value); err != nil {
+				return cnt, errors.Errorf("store config entry '%s', %v", key, err)
+			}
+			cnt++
+		}
+		if err := t.encryptPlainHistory(key, encryptPlain); err != nil {
+			return cnt, errors.Errorf("encrypt history of config entry '%s', %v", key, err)
+		}
+	}
+
+	return cnt, nil
+}
+
+func (t *implConfigRepository) encryptPlainHistory(key string, encryptPlain func(key, value string) (string, error)) error {
+
+	history, err := t.History(key)
+	if err != nil {
+		return err
+	}
+
+	isPlain := func(value string) bool {
+		return value != "" && !sprintutils.IsEncryptedValue(value)
+	}
+
+	for _, change := range history {
+		if !isPlain(change.OldValue) && !isPlain(change.NewValue) {
+			continue
+		}
+		if change.OldValue, err = encryptPlain(key, change.OldValue); err != nil {
+			return err
+		}
+		if change.NewValue, err = encryptPlain(key, change.NewValue); err != nil {
+			return err
+		}
+		if err := t.putHistory(key, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"github.com/codeallergy/glue"
+	"github.com/keyvalstore/boltstore"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+/**
+	Property resolver of the test values that could be changed at runtime.
+ */
+
+type mapPropertyResolver struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (t *mapPropertyResolver) Priority() int {
+	return 100
+}
+
+func (t *mapPropertyResolver) GetProperty(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	value, ok := t.values[key]
+	return value, ok
+}
+
+func (t *mapPropertyResolver) set(key, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.values[key] = value
+}
+
+/**
+	Creates the context of the beans with 'config-store' in the temporary directory of the test.
+ */
+
+func newStoreContext(t *testing.T, beans ...interface{}) (glue.Context, func()) {
+
+	configStore, err := boltstore.New("config-store", filepath.Join(t.TempDir(), "config.db"), 0600)
+	require.NoError(t, err)
+
+	ctx, err := glue.New(append(beans, configStore)...)
+	require.NoError(t, err)
+
+	return ctx, func() {
+		ctx.Close()
+	}
+}
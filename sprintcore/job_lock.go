@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/keyvalstore/store"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var JobLockClass = reflect.TypeOf((*JobLock)(nil)).Elem()
+
+/**
+	Lease based lock using by JobService to run singleton jobs only on one node at the time.
+ */
+
+type JobLock interface {
+
+	/**
+	Tries to acquire the lease on the lock for the owner, returns false if the lease is held by another owner.
+	 */
+
+	Acquire(name, owner string, ttl time.Duration) (bool, error)
+
+	/**
+	Extends the lease of the owner, returns false if the lease was lost.
+	 */
+
+	Renew(name, owner string, ttl time.Duration) (bool, error)
+
+	/**
+	Releases the lease if it is held by the owner.
+	 */
+
+	Release(name, owner string) error
+
+	/**
+	Gets the current owner of the lease, returns empty string if the lease is free or expired.
+	 */
+
+	Holder(name string) (owner string, expiresAt time.Time, err error)
+
+}
+
+var (
+	LockBucket = "lock"
+)
+
+/**
+	Lock implementation that keeps leases in the 'config-store'.
+	Lock is shared between nodes only if the 'config-store' bean is backed by the shared storage.
+
+	Leases are taken in the store transaction if the store supports atomic update (bolt, badger) and verified by re-reading after the write.
+	Without atomic update the lease is guarded only by the in-process mutex, so two processes sharing the store could both take an expired lease.
+ */
+
+type implStoreJobLock struct {
+	Store   store.DataStore `inject:"bean=config-store"`
+
+	mu      sync.Mutex
+}
+
+type jobLease struct {
+	Owner     string   `json:"owner"`
+	ExpiresAt int64    `json:"expiresAt"`  // unix millis
+}
+
+/**
+	Transactional read-modify-write supported by bolt and badger stores, the callback returns false to cancel the write.
+ */
+
+type atomicUpdateStore interface {
+	UpdateRaw(ctx context.Context, key []byte, cb func(entry *store.RawEntry) bool) error
+}
+
+func StoreJobLock() JobLock {
+	return &implStoreJobLock{}
+}
+
+func (t *implStoreJobLock) Acquire(name, owner string, ttl time.Duration) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.update(name, owner, func(lease *jobLease, now int64) bool {
+		return lease == nil || lease.Owner == owner || lease.ExpiresAt <= now
+	}, ttl)
+}
+
+func (t *implStoreJobLock) Renew(name, owner string, ttl time.Duration) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.update(name, owner, func(lease *jobLease, now int64) bool {
+		return lease != nil && lease.Owner == owner
+	}, ttl)
+}
+
+/**
+	Releases the lease by writing it expired, so the next owner takes it in the same way as the expired one.
+ */
+
+func (t *implStoreJobLock) Release(name, owner string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := t.update(name, owner, func(lease *jobLease, now int64) bool {
+		return lease != nil && lease.Owner == owner
+	}, 0)
+	return err
+}
+
+func (t *implStoreJobLock) Holder(name string) (string, time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lease, err := t.load(name)
+	if err != nil || lease == nil || lease.ExpiresAt <= time.Now().UnixMilli() {
+		return "", time.Time{}, err
+	}
+
+	return lease.Owner, time.UnixMilli(lease.ExpiresAt), nil
+}
+
+/**
+	Writes the lease of the owner if the condition holds on the current lease, then re-reads the lease to verify the ownership.
+	Zero ttl writes the expired lease.
+ */
+
+func (t *implStoreJobLock) update(name, owner string, cond func(lease *jobLease, now int64) bool, ttl time.Duration) (bool, error) {
+
+	now := time.Now().UnixMilli()
+	expiresAt := now
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UnixMilli()
+	}
+
+	value, err := json.Marshal(&jobLease{
+		Owner:     owner,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if s, ok := t.Store.(atomicUpdateStore); ok {
+
+		var refused bool
+		var decodeErr error
+		err = s.UpdateRaw(context.Background(), leaseKey(name), func(entry *store.RawEntry) bool {
+			lease, err := decodeLease(entry.Value)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			if !cond(lease, now) {
+				refused = true
+				return false
+			}
+			entry.Value = value
+			entry.Ttl = store.NoTTL
+			return true
+		})
+		if decodeErr != nil {
+			return false, decodeErr
+		}
+		if refused {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+	} else {
+
+		lease, err := t.load(name)
+		if err != nil {
+			return false, err
+		}
+		if !cond(lease, now) {
+			return false, nil
+		}
+		if err := t.Store.Set(context.Background()).ByRawKey(leaseKey(name)).Binary(value); err != nil {
+			return false, err
+		}
+
+	}
+
+	lease, err := t.load(name)
+	if err != nil {
+		return false, err
+	}
+	return lease != nil && lease.Owner == owner && lease.ExpiresAt == expiresAt, nil
+}
+
+func (t *implStoreJobLock) load(name string) (*jobLease, error) {
+	value, err := t.Store.Get(context.Background()).ByRawKey(leaseKey(name)).ToBinary()
+	if err != nil {
+		return nil, err
+	}
+	return decodeLease(value)
+}
+
+func leaseKey(name string) []byte {
+	return []byte(fmt.Sprintf("%s:%s", LockBucket, name))
+}
+
+func decodeLease(value []byte) (*jobLease, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	lease := new(jobLease)
+	if err := json.Unmarshal(value, lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"fmt"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type jobLockHolder struct {
+	Locks []sprintcore.JobLock `inject`
+}
+
+func newJobLocks(t *testing.T) ([]sprintcore.JobLock, func()) {
+
+	holder := new(jobLockHolder)
+
+	// two lock beans over the same store act as two nodes
+	_, done := newStoreContext(t, sprintcore.StoreJobLock(), sprintcore.StoreJobLock(), holder)
+	require.Equal(t, 2, len(holder.Locks))
+
+	return holder.Locks, done
+}
+
+func TestJobLockAcquireExpiredConcurrently(t *testing.T) {
+
+	locks, done := newJobLocks(t)
+	defer done()
+
+	ok, err := locks[0].Acquire("backup", "old", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	var winners int32
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := locks[i % len(locks)].Acquire("backup", fmt.Sprintf("node-%d", i), time.Minute)
+			if err != nil {
+				errs <- err
+			}
+			if ok {
+				atomic.AddInt32(&winners, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), winners)
+
+	holder, expiresAt, err := locks[0].Holder("backup")
+	require.NoError(t, err)
+	require.NotEqual(t, "", holder)
+	require.True(t, expiresAt.After(time.Now()))
+}
+
+func TestJobLockRenewRelease(t *testing.T) {
+
+	locks, done := newJobLocks(t)
+	defer done()
+
+	ok, err := locks[0].Acquire("backup", "node-a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = locks[1].Acquire("backup", "node-b", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = locks[1].Renew("backup", "node-b", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = locks[0].Renew("backup", "node-a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, locks[1].Release("backup", "node-b"))
+	holder, _, err := locks[0].Holder("backup")
+	require.NoError(t, err)
+	require.Equal(t, "node-a", holder)
+
+	require.NoError(t, locks[0].Release("backup", "node-a"))
+	holder, _, err = locks[0].Holder("backup")
+	require.NoError(t, err)
+	require.Equal(t, "", holder)
+
+	ok, err = locks[1].Acquire("backup", "node-b", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -7,25 +7,154 @@ package sprintcore
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/keyvalstore/store"
 	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
-var ErrJobNotFound = errors.New("job not found")
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobNotRunning = errors.New("job is not running")
+	ErrJobAlreadyRunning = errors.New("job is already running")
+	ErrJobLocked         = errors.New("job is locked by another node")
+)
+
+var (
+	JobBucket = "job"
+
+	JobHistoryLimit = 20
+)
+
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+/**
+	Concurrency policies of the job defined by 'job.{name}.concurrency' property.
+ */
+
+const (
+	JobConcurrencyAllow = "allow"  // runs executions in parallel
+	JobConcurrencySkip  = "skip"   // skips the execution if job is already running
+	JobConcurrencyQueue = "queue"  // waits until the previous execution finishes
+)
+
+const (
+	schedulerUser = "scheduler"
+	systemUser    = "system"
+)
+
+/**
+	Record of the single job run stored in the 'job' bucket of the 'config-store'.
+ */
+
+type JobExecution struct {
+	Id        string          `json:"id"`
+	Name      string          `json:"name"`
+	User      string          `json:"user"`
+	Node      string          `json:"node"`
+	Started   int64           `json:"started"`   // unix millis
+	Finished  int64           `json:"finished"`  // unix millis, zero if still running
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Attempts  []*JobAttempt   `json:"attempts,omitempty"`
+}
+
+type JobAttempt struct {
+	Started   int64    `json:"started"`   // unix millis
+	Finished  int64    `json:"finished"`  // unix millis, zero if still running
+	Error     string   `json:"error,omitempty"`
+}
+
+type jobOutputKey struct{}
+
+type jobOutput struct {
+	ch  chan string
+}
+
+/**
+	Emits the progress line from the running job, followers of the execution receive it through the control stream.
+ */
+
+func JobOutput(ctx context.Context, line string) {
+	if out, ok := ctx.Value(jobOutputKey{}).(*jobOutput); ok {
+		select {
+		case out.ch <- line:
+		default:
+			// never block the job on the slow follower
+		}
+	}
+}
+
+func JobOutputf(ctx context.Context, format string, args ...interface{}) {
+	JobOutput(ctx, fmt.Sprintf(format, args...))
+}
 
 type implJobService struct {
 	Log           *zap.Logger              `inject`
+	Application   sprint.Application       `inject`
+	Properties    glue.Properties          `inject`
+	Store         store.DataStore          `inject:"bean=config-store"`
+	NodeService   sprint.NodeService       `inject`
+	AutoupdateService  sprint.AutoupdateService  `inject:"optional"`
+	JobLock       JobLock                  `inject:"optional"`
+
+	MaxConcurrency  int   `value:"job.max-concurrency,default=4"`  // zero for unlimited, the schema rejects negative
+
+	HistoryMaxRecords  int            `value:"job.history.max-records,default=100"`  // zero or negative for unlimited
+	HistoryRetention   time.Duration  `value:"job.history.retention,default=0s"`     // zero keeps records until the limit
 
 	muJobs  sync.Mutex
-	jobs    []*sprint.JobInfo
+	jobs    []*jobEntry
+	running map[string]map[string]context.CancelFunc  // job name -> execution id -> cancel function
+
+	workers       chan struct{}  // global pool of job executions, nil if unlimited
+	wakeCh        chan struct{}
+	ctx           context.Context     // scope of the scheduler and job runs, done on application shutdown or destroy
+	cancelFn      context.CancelFunc
+}
+
+type jobEntry struct {
+	info      *sprint.JobInfo
+	schedule  sprintutils.Schedule  // nil for the on-demand jobs
+	queue     chan struct{}         // serializes executions for the queue policy
+	next      time.Time
+	last      time.Time
 }
 
 func JobService() sprint.JobService {
-	return &implJobService{}
+	return &implJobService{
+		running: make(map[string]map[string]context.CancelFunc),
+		wakeCh:  make(chan struct{}, 1),
+	}
+}
+
+func (t *implJobService) PostConstruct() error {
+	if t.MaxConcurrency > 0 {
+		t.workers = make(chan struct{}, t.MaxConcurrency)
+	}
+	t.ctx, t.cancelFn = context.WithCancel(t.Application)
+	go t.schedulerLoop(t.ctx)
+	return nil
+}
+
+func (t *implJobService) Destroy() error {
+	if t.cancelFn != nil {
+		t.cancelFn()
+	}
+	return nil
 }
 
 func (t *implJobService) ListJobs() ([]string, error) {
@@ -33,27 +162,70 @@ func (t *implJobService) ListJobs() ([]string, error) {
 	defer t.muJobs.Unlock()
 
 	var list []string
-	for _, job := range t.jobs {
-		list = append(list, job.Name)
+	for _, e := range t.jobs {
+		list = append(list, e.info.Name)
 	}
 
 	return list, nil
 }
 
 func (t *implJobService) AddJob(job *sprint.JobInfo) error {
+
+	e := &jobEntry{
+		info:  job,
+		queue: make(chan struct{}, 1),
+	}
+
+	if job.Schedule != "" {
+		schedule, err := sprintutils.ParseSchedule(job.Schedule)
+		if err != nil {
+			return errors.Errorf("job '%s' has invalid schedule, %v", job.Name, err)
+		}
+		e.schedule = schedule
+		e.next = schedule.Next(time.Now())
+	}
+
 	t.muJobs.Lock()
-	defer t.muJobs.Unlock()
+	t.jobs = append(t.jobs, e)
+	t.muJobs.Unlock()
 
-	t.jobs = append(t.jobs, job)
+	t.wakeUp()
 	return nil
 }
 
+/**
+	Cancels all in-flight executions of the job, the job stays in the schedule list.
+ */
+
 func (t *implJobService) CancelJob(name string) error {
 	t.muJobs.Lock()
 	defer t.muJobs.Unlock()
 
-	for i, job := range t.jobs {
-		if job.Name == name {
+	if t.findEntry(name) == nil {
+		return ErrJobNotFound
+	}
+
+	executions := t.running[name]
+	if len(executions) == 0 {
+		return ErrJobNotRunning
+	}
+
+	for _, cancel := range executions {
+		cancel()
+	}
+	return nil
+}
+
+/**
+	Removes the job from the schedule list, in-flight executions keep running.
+ */
+
+func (t *implJobService) RemoveJob(name string) error {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+
+	for i, e := range t.jobs {
+		if e.info.Name == name {
 			t.jobs = append(t.jobs[:i], t.jobs[i+1:]...)
 			return nil
 		}
@@ -62,50 +234,631 @@ func (t *implJobService) CancelJob(name string) error {
 	return ErrJobNotFound
 }
 
-func (t *implJobService) RunJob(ctx context.Context, name string) (err error) {
+/**
+	Runs the job in the scope of the application and waits for the result until the context of the caller is done.
+	The end of the caller request does not cancel the job, use CancelJob for that.
+ */
+
+func (t *implJobService) RunJob(ctx context.Context, name string) error {
 
-	defer sprintutils.PanicToError(&err)
+	result := make(chan error, 1)
+	go func() {
+		result <- t.executeJob(nil, name, systemUser)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/**
+	Runs the job and records the execution in the history.
+	The context of the run derives from the scope of the service, only its cancel function is kept for CancelJob.
+ */
 
-	job, err := t.findJob(name)
+func (t *implJobService) executeJob(out *jobOutput, name, username string) error {
+
+	e, err := t.findJobEntry(name)
 	if err != nil {
 		return err
 	}
 
+	policy := t.concurrencyPolicy(name)
+
+	started := time.Now()
+	exec := &JobExecution{
+		Id:      fmt.Sprintf("%019d", started.UnixNano()),
+		Name:    name,
+		User:    username,
+		Node:    t.NodeService.NodeIdHex(),
+		Status:  JobRunning,
+	}
+
+	appCtx := t.ctx
+	if appCtx == nil {
+		appCtx = t.Application
+	}
+	if out != nil {
+		appCtx = context.WithValue(appCtx, jobOutputKey{}, out)
+	}
+
+	runCtx, cancel := context.WithCancel(appCtx)
+	defer cancel()
+
+	if !t.registerExecution(name, exec.Id, cancel, policy == JobConcurrencySkip) {
+		return ErrJobAlreadyRunning
+	}
+	defer t.unregisterExecution(name, exec.Id)
+
+	if policy == JobConcurrencyQueue {
+		select {
+		case e.queue <- struct{}{}:
+			defer func() { <-e.queue }()
+		case <-runCtx.Done():
+			return runCtx.Err()
+		}
+	}
+
+	if t.workers != nil {
+		select {
+		case t.workers <- struct{}{}:
+			defer func() { <-t.workers }()
+		case <-runCtx.Done():
+			return runCtx.Err()
+		}
+	}
+
+	if t.Properties.GetBool(fmt.Sprintf("job.%s.singleton", name), false) {
+		release, err := t.acquireLease(runCtx, name, cancel)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	if t.AutoupdateService != nil {
+		// restart by the new binary must not kill the running job
+		handle := t.AutoupdateService.Freeze(name)
+		defer t.AutoupdateService.Unfreeze(handle)
+	}
+
+	exec.Started = time.Now().UnixMilli()
+	err = t.runWithRetry(runCtx, e.info, exec)
+	cancelled := runCtx.Err() != nil
+
+	exec.Finished = time.Now().UnixMilli()
+	if err != nil {
+		if cancelled {
+			exec.Status = JobCancelled
+		} else {
+			exec.Status = JobFailed
+		}
+		exec.Error = err.Error()
+	} else {
+		exec.Status = JobSucceeded
+	}
+	t.saveExecution(exec)
+	t.pruneHistory(name)
+
+	return err
+}
+
+/**
+	Runs the job until success or max attempts defined by 'job.{name}.retry.*' properties.
+	Stops retrying on cancel of the execution or on application shutdown.
+ */
+
+func (t *implJobService) runWithRetry(ctx context.Context, job *sprint.JobInfo, exec *JobExecution) (err error) {
+
+	maxAttempts, backoff := t.retryPolicy(job.Name)
+
+	for attempt := 1; ; attempt++ {
+
+		a := &JobAttempt{Started: time.Now().UnixMilli()}
+		exec.Attempts = append(exec.Attempts, a)
+		t.saveExecution(exec)
+
+		err = t.doRunJob(ctx, job)
+
+		a.Finished = time.Now().UnixMilli()
+		if err == nil {
+			return nil
+		}
+		a.Error = err.Error()
+
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff.Delay(attempt)
+		t.Log.Warn("JobRetry", zap.String("jobName", job.Name), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		t.saveExecution(exec)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+}
+
+func (t *implJobService) retryPolicy(name string) (int, sprintutils.Backoff) {
+
+	prefix := fmt.Sprintf("job.%s.retry", name)
+
+	maxAttempts := t.Properties.GetInt(prefix + ".max-attempts", 1)
+	backoff := sprintutils.Backoff{
+		Initial: t.Properties.GetDuration(prefix + ".initial-backoff", time.Second),
+		Max:     t.Properties.GetDuration(prefix + ".max-backoff", time.Minute),
+	}
+
+	if jitter := t.Properties.GetString(prefix + ".jitter", ""); jitter != "" {
+		if value, err := strconv.ParseFloat(jitter, 64); err == nil {
+			backoff.Jitter = value
+		} else {
+			t.Log.Warn("JobRetryJitter", zap.String("jobName", name), zap.String("jitter", jitter), zap.Error(err))
+		}
+	}
+
+	return maxAttempts, backoff
+}
+
+/**
+	Acquires the lease for the singleton job and keeps renewing it in background.
+	Cancels the execution if the lease was lost.
+ */
+
+func (t *implJobService) acquireLease(ctx context.Context, name string, cancel context.CancelFunc) (func(), error) {
+
+	if t.JobLock == nil {
+		return nil, errors.Errorf("singleton job '%s' needs JobLock bean in context", name)
+	}
+
+	owner := t.NodeService.NodeIdHex()
+	ttl := t.Properties.GetDuration(fmt.Sprintf("job.%s.lease-ttl", name), 30 * time.Second)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	ok, err := t.JobLock.Acquire(name, owner, ttl)
+	if err != nil {
+		return nil, errors.Errorf("acquire lease for job '%s', %v", name, err)
+	}
+	if !ok {
+		holder, _, _ := t.JobLock.Holder(name)
+		return nil, errors.Wrapf(ErrJobLocked, "holder '%s'", holder)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ok, err := t.JobLock.Renew(name, owner, ttl)
+				if err != nil || !ok {
+					t.Log.Error("JobLeaseLost", zap.String("jobName", name), zap.String("owner", owner), zap.Error(err))
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := t.JobLock.Release(name, owner); err != nil {
+			t.Log.Error("JobLeaseRelease", zap.String("jobName", name), zap.String("owner", owner), zap.Error(err))
+		}
+	}, nil
+}
+
+/**
+	Runs the job on behalf of the user and passes the job output to the callback until the job finishes.
+	If the follower disconnects, then the job keeps running in background.
+ */
+
+func (t *implJobService) FollowJob(ctx context.Context, username, name string, cb func(line string) bool) error {
+
+	if _, err := t.findJobEntry(name); err != nil {
+		return err
+	}
+
+	out := &jobOutput{ch: make(chan string, 1024)}
+	result := make(chan error, 1)
+
+	go func() {
+		result <- t.executeJob(out, name, username)
+	}()
+
+	for {
+		select {
+		case line := <-out.ch:
+			if !cb(line) {
+				return errors.New("follower disconnected")
+			}
+		case err := <-result:
+			for {
+				select {
+				case line := <-out.ch:
+					if !cb(line) {
+						return err
+					}
+				default:
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *implJobService) concurrencyPolicy(name string) string {
+	policy := t.Properties.GetString(fmt.Sprintf("job.%s.concurrency", name), JobConcurrencyAllow)
+	switch policy {
+	case JobConcurrencyAllow, JobConcurrencySkip, JobConcurrencyQueue:
+		return policy
+	default:
+		t.Log.Warn("JobConcurrencyPolicy", zap.String("jobName", name), zap.String("policy", policy))
+		return JobConcurrencyAllow
+	}
+}
+
+func (t *implJobService) doRunJob(ctx context.Context, job *sprint.JobInfo) (err error) {
+	defer sprintutils.PanicToError(&err)
 	return job.ExecutionFn(ctx)
 }
 
-func (t *implJobService) findJob(name string) (*sprint.JobInfo, error) {
+func (t *implJobService) saveExecution(exec *JobExecution) {
+	value, err := json.Marshal(exec)
+	if err == nil {
+		err = t.Store.Set(context.Background()).ByKey("%s:%s:%s", JobBucket, exec.Name, exec.Id).Binary(value)
+	}
+	if err != nil {
+		t.Log.Error("JobSaveExecution", zap.String("jobName", exec.Name), zap.String("id", exec.Id), zap.Error(err))
+	}
+}
+
+/**
+	Removes the oldest executions of the job beyond 'job.history.max-records' and executions older than 'job.history.retention'.
+	Unfinished executions, including the ones running on other nodes, are never removed.
+ */
+
+func (t *implJobService) pruneHistory(name string) {
+
+	list, err := t.JobHistory(name, 0)
+	if err != nil {
+		t.Log.Error("JobPruneHistory", zap.String("jobName", name), zap.Error(err))
+		if len(list) == 0 {
+			return
+		}
+	}
+
+	excess := 0
+	if t.HistoryMaxRecords > 0 && len(list) > t.HistoryMaxRecords {
+		excess = len(list) - t.HistoryMaxRecords
+	}
+
+	var deadline int64
+	if t.HistoryRetention > 0 {
+		deadline = time.Now().Add(-t.HistoryRetention).UnixMilli()
+	}
+
+	for _, exec := range list {
+		if exec.Finished == 0 || exec.Status == JobRunning {
+			continue
+		}
+		if excess > 0 {
+			excess--
+		} else if deadline == 0 || exec.Finished >= deadline {
+			continue
+		}
+		if err := t.Store.Remove(context.Background()).ByKey("%s:%s:%s", JobBucket, name, exec.Id).Do(); err != nil {
+			t.Log.Error("JobPruneHistory", zap.String("jobName", name), zap.String("id", exec.Id), zap.Error(err))
+			return
+		}
+	}
+}
+
+/**
+	Returns the latest executions of the job, the oldest first.
+ */
+
+func (t *implJobService) JobHistory(name string, limit int) ([]*JobExecution, error) {
+
+	var list []*JobExecution
+	var lastErr error
+
+	prefixLen := len(JobBucket) + len(name) + 2
+
+	err := t.Store.Enumerate(context.Background()).
+		ByPrefix("%s:%s:", JobBucket, name).
+		WithBatchSize(256).
+		Do(func(entry *store.RawEntry) bool {
+			// the prefix of job 'a' matches executions of job 'a:b' as well
+			if !isDigits(entry.Key[prefixLen:]) {
+				return true
+			}
+			exec := new(JobExecution)
+			if err := json.Unmarshal(entry.Value, exec); err != nil {
+				lastErr = errors.Errorf("invalid job execution record '%s', %v", string(entry.Key), err)
+				return true
+			}
+			list = append(list, exec)
+			if limit > 0 && len(list) > limit {
+				list = list[1:]
+			}
+			return true
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, lastErr
+}
+
+func (t *implJobService) describeStatus(name string) (string, error) {
+
+	if _, err := t.findJobEntry(name); err != nil {
+		return "", err
+	}
+
+	list, err := t.JobHistory(name, 1)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("%s, running %d\n", name, len(t.runningIds(name))))
+
+	if t.JobLock != nil {
+		if holder, expiresAt, err := t.JobLock.Holder(name); err == nil && holder != "" {
+			out.WriteString(fmt.Sprintf("lease held by node %s until %s\n", holder, formatJobTime(expiresAt)))
+		}
+	}
+
+	for _, exec := range list {
+		out.WriteString(fmt.Sprintf("last %s, %s, started %s, user '%s', node %s\n", exec.Id, exec.Status, formatJobTime(time.UnixMilli(exec.Started)), exec.User, exec.Node))
+		for i, a := range exec.Attempts {
+			finished := "-"
+			if a.Finished != 0 {
+				finished = formatJobTime(time.UnixMilli(a.Finished))
+			}
+			out.WriteString(fmt.Sprintf("  attempt %d, started %s, finished %s", i+1, formatJobTime(time.UnixMilli(a.Started)), finished))
+			if a.Error != "" {
+				out.WriteString(fmt.Sprintf(", error: %s", a.Error))
+			}
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String(), nil
+}
+
+func (t *implJobService) describeHistory(name string, limit int) (string, error) {
+
+	list, err := t.JobHistory(name, limit)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, exec := range list {
+		started := time.UnixMilli(exec.Started)
+		duration := "-"
+		if exec.Finished != 0 {
+			duration = time.UnixMilli(exec.Finished).Sub(started).String()
+		}
+		out.WriteString(fmt.Sprintf("%s, %s, started %s, duration %s, attempts %d, user '%s'", exec.Id, exec.Status, started.Format(time.RFC3339), duration, len(exec.Attempts), exec.User))
+		if exec.Error != "" {
+			out.WriteString(fmt.Sprintf(", error: %s", exec.Error))
+		}
+		out.WriteByte('\n')
+	}
+	return out.String(), nil
+}
+
+func (t *implJobService) findJobEntry(name string) (*jobEntry, error) {
 	t.muJobs.Lock()
 	defer t.muJobs.Unlock()
 
-	for _, job := range t.jobs {
-		if job.Name == name {
-			return job, nil
-		}
+	if e := t.findEntry(name); e != nil {
+		return e, nil
 	}
 
 	return nil, ErrJobNotFound
 }
 
+// must be called under muJobs lock
+func (t *implJobService) findEntry(name string) *jobEntry {
+	for _, e := range t.jobs {
+		if e.info.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func (t *implJobService) registerExecution(name, id string, cancel context.CancelFunc, skipIfRunning bool) bool {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+
+	executions, ok := t.running[name]
+	if !ok {
+		executions = make(map[string]context.CancelFunc)
+		t.running[name] = executions
+	} else if skipIfRunning && len(executions) > 0 {
+		return false
+	}
+	executions[id] = cancel
+	return true
+}
+
+func (t *implJobService) runningIds(name string) []string {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+	var list []string
+	for id := range t.running[name] {
+		list = append(list, id)
+	}
+	return list
+}
+
+func (t *implJobService) isRunning(name string) bool {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+	return len(t.running[name]) > 0
+}
+
+func (t *implJobService) unregisterExecution(name, id string) {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+
+	if executions, ok := t.running[name]; ok {
+		delete(executions, id)
+		if len(executions) == 0 {
+			delete(t.running, name)
+		}
+	}
+}
+
+func (t *implJobService) wakeUp() {
+	select {
+	case t.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+/**
+	Fires scheduled jobs until the application shutdown.
+ */
+
+func (t *implJobService) schedulerLoop(ctx context.Context) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Log.Error("RecoverJobScheduler", zap.String("err", fmt.Sprintf("%v", r)))
+		}
+	}()
+
+	for {
+
+		due, next := t.dueJobs(time.Now())
+		for _, name := range due {
+			go t.fireJob(name)
+		}
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-t.wakeCh:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+}
+
+func (t *implJobService) dueJobs(now time.Time) (due []string, next time.Time) {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+
+	for _, e := range t.jobs {
+		if e.schedule == nil || e.next.IsZero() {
+			continue
+		}
+		if !e.next.After(now) {
+			due = append(due, e.info.Name)
+			e.last = now
+			e.next = e.schedule.Next(now)
+			if e.next.IsZero() {
+				continue
+			}
+		}
+		if next.IsZero() || e.next.Before(next) {
+			next = e.next
+		}
+	}
+
+	return
+}
+
+func (t *implJobService) fireJob(name string) {
+	err := t.executeJob(nil, name, schedulerUser)
+	if err == ErrJobAlreadyRunning || errors.Cause(err) == ErrJobLocked {
+		t.Log.Info("JobScheduledSkip", zap.String("jobName", name), zap.Error(err))
+	} else if err != nil {
+		t.Log.Error("JobScheduledRun", zap.String("jobName", name), zap.Error(err))
+	}
+}
+
+func (t *implJobService) describeJobs() string {
+	t.muJobs.Lock()
+	defer t.muJobs.Unlock()
+
+	var out strings.Builder
+	for _, e := range t.jobs {
+		schedule := e.info.Schedule
+		if schedule == "" {
+			schedule = "on-demand"
+		}
+		out.WriteString(fmt.Sprintf("%s, schedule '%s', next %s, last %s, running %d\n", e.info.Name, schedule, formatJobTime(e.next), formatJobTime(e.last), len(t.running[e.info.Name])))
+	}
+	return out.String()
+}
+
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
 
 func (t *implJobService) ExecuteCommand(cmd string, args []string) (string, error) {
+	return t.ExecuteUserCommand(systemUser, cmd, args)
+}
+
+/**
+	Executes command on job service on behalf of the user, that would be recorded in the job history.
+ */
+
+func (t *implJobService) ExecuteUserCommand(username, cmd string, args []string) (string, error) {
 
 	switch cmd {
 	case "list":
-		list, err := t.ListJobs()
-		if err != nil {
-			return "", err
-		}
-		return strings.Join(list, "\n"), nil
+		return t.describeJobs(), nil
 
 	case "run":
 		if len(args) < 1 {
 			return "Usage: job run name", nil
 		}
 		jobName := args[0]
+		if t.concurrencyPolicy(jobName) == JobConcurrencySkip && t.isRunning(jobName) {
+			return "", errors.Errorf("run of job '%s' was skipped, %v", jobName, ErrJobAlreadyRunning)
+		}
 		go func() {
 
-			err := t.RunJob(context.Background(), jobName)
+			err := t.executeJob(nil, jobName, username)
 			if err != nil {
 				t.Log.Error("JobRun", zap.String("jobName", jobName), zap.Error(err))
 			}
@@ -124,8 +877,40 @@ func (t *implJobService) ExecuteCommand(cmd string, args []string) (string, erro
 		}
 		return"OK", nil
 
+	case "remove":
+		if len(args) < 1 {
+			return "Usage: job remove name", nil
+		}
+		jobName := args[0]
+		err := t.RemoveJob(jobName)
+		if err != nil {
+			return "", errors.Errorf("remove of job '%s' was failed, %v", jobName, err)
+		}
+		return "OK", nil
+
+	case "status":
+		if len(args) < 1 {
+			return "Usage: job status name", nil
+		}
+		return t.describeStatus(args[0])
+
+	case "history":
+		if len(args) < 1 {
+			return "Usage: job history name [limit]", nil
+		}
+		jobName := args[0]
+		limit := JobHistoryLimit
+		if len(args) > 1 {
+			var err error
+			limit, err = strconv.Atoi(args[1])
+			if err != nil {
+				return "", errors.Errorf("parsing limit '%s', %v", args[1], err)
+			}
+		}
+		return t.describeHistory(jobName, limit)
+
 	default:
 		return "", errors.Errorf("unknown job command '%s'", cmd)
 	}
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,384 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type jobHistory interface {
+	JobHistory(name string, limit int) ([]*sprintcore.JobExecution, error)
+}
+
+type jobServiceHolder struct {
+	JobService sprint.JobService `inject`
+}
+
+func newJobService(t *testing.T, values map[string]string) (sprint.JobService, func()) {
+
+	holder := new(jobServiceHolder)
+	_, done := newStoreContext(t,
+		sprintapp.Application("test"),
+		sprintapp.ApplicationFlags(0),
+		&mapPropertyResolver{values: values},
+		zap.NewNop(),
+		sprintcore.ConfigRepository(100),
+		sprintcore.NodeService(),
+		sprintcore.JobService(),
+		holder,
+	)
+
+	return holder.JobService, done
+}
+
+func getJobHistory(t *testing.T, service sprint.JobService, name string) []*sprintcore.JobExecution {
+	history, ok := service.(jobHistory)
+	require.True(t, ok)
+	list, err := history.JobHistory(name, 0)
+	require.NoError(t, err)
+	return list
+}
+
+func TestJobSchedule(t *testing.T) {
+
+	service, done := newJobService(t, nil)
+	defer done()
+
+	err := service.AddJob(&sprint.JobInfo{
+		Name:     "broken",
+		Schedule: "@every 1ms",
+		ExecutionFn: func(ctx context.Context) error {
+			return nil
+		},
+	})
+	require.Error(t, err)
+
+	fired := make(chan struct{}, 1)
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name:     "ticker",
+		Schedule: "@every 1s",
+		ExecutionFn: func(ctx context.Context) error {
+			select {
+			case fired <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	}))
+
+	list, err := service.ListJobs()
+	require.NoError(t, err)
+	require.Equal(t, []string{"ticker"}, list)
+
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "scheduled job did not run")
+	}
+}
+
+func TestJobHistoryPrune(t *testing.T) {
+
+	service, done := newJobService(t, map[string]string{
+		"job.history.max-records": "3",
+	})
+	defer done()
+
+	for _, name := range []string{"a", "a:b"} {
+		require.NoError(t, service.AddJob(&sprint.JobInfo{
+			Name: name,
+			ExecutionFn: func(ctx context.Context) error {
+				return nil
+			},
+		}))
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, service.RunJob(context.Background(), "a"))
+	}
+	require.NoError(t, service.RunJob(context.Background(), "a:b"))
+
+	list := getJobHistory(t, service, "a")
+	require.Equal(t, 3, len(list))
+	for _, exec := range list {
+		require.Equal(t, "a", exec.Name)
+	}
+
+	list = getJobHistory(t, service, "a:b")
+	require.Equal(t, 1, len(list))
+}
+
+func TestJobHistoryPruneKeepsRunning(t *testing.T) {
+
+	service, done := newJobService(t, map[string]string{
+		"job.history.max-records": "3",
+	})
+	defer done()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := true
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "a",
+		ExecutionFn: func(ctx context.Context) error {
+			if first {
+				first = false
+				close(started)
+				<-release
+			}
+			return nil
+		},
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		result <- service.RunJob(context.Background(), "a")
+	}()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, service.RunJob(context.Background(), "a"))
+	}
+
+	list := getJobHistory(t, service, "a")
+	require.Equal(t, 3, len(list))
+	require.Equal(t, sprintcore.JobRunning, list[0].Status)
+
+	close(release)
+	require.NoError(t, <-result)
+}
+
+func TestRunJobOutlivesCaller(t *testing.T) {
+
+	service, done := newJobService(t, nil)
+	defer done()
+
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "long",
+		ExecutionFn: func(ctx context.Context) error {
+			select {
+			case <-release:
+				finished <- nil
+			case <-ctx.Done():
+				finished <- ctx.Err()
+			}
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, service.RunJob(ctx, "long"))
+
+	// end of the caller request does not cancel the job
+	close(release)
+	select {
+	case err := <-finished:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "job did not finish")
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+
+	service, done := newJobService(t, nil)
+	defer done()
+
+	started := make(chan struct{})
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "blocking",
+		ExecutionFn: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		result <- service.RunJob(context.Background(), "blocking")
+	}()
+	<-started
+
+	require.NoError(t, service.CancelJob("blocking"))
+	select {
+	case err := <-result:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "job was not cancelled")
+	}
+
+	list := getJobHistory(t, service, "blocking")
+	require.Equal(t, 1, len(list))
+	require.Equal(t, sprintcore.JobCancelled, list[0].Status)
+
+	require.Equal(t, sprintcore.ErrJobNotRunning, service.CancelJob("blocking"))
+}
+
+func TestJobSkipPolicy(t *testing.T) {
+
+	service, done := newJobService(t, map[string]string{
+		"job.blocking.concurrency": "skip",
+	})
+	defer done()
+
+	started := make(chan struct{})
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "blocking",
+		ExecutionFn: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		result <- service.RunJob(context.Background(), "blocking")
+	}()
+	<-started
+
+	require.Equal(t, sprintcore.ErrJobAlreadyRunning, service.RunJob(context.Background(), "blocking"))
+
+	require.NoError(t, service.CancelJob("blocking"))
+	select {
+	case err := <-result:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "job was not cancelled")
+	}
+
+	list := getJobHistory(t, service, "blocking")
+	require.Equal(t, 1, len(list))
+}
+
+func TestJobRetry(t *testing.T) {
+
+	service, done := newJobService(t, map[string]string{
+		"job.flaky.retry.max-attempts":    "3",
+		"job.flaky.retry.initial-backoff": "1ms",
+	})
+	defer done()
+
+	calls := 0
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "flaky",
+		ExecutionFn: func(ctx context.Context) error {
+			if calls++; calls < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		},
+	}))
+
+	require.NoError(t, service.RunJob(context.Background(), "flaky"))
+
+	list := getJobHistory(t, service, "flaky")
+	require.Equal(t, 1, len(list))
+	require.Equal(t, sprintcore.JobSucceeded, list[0].Status)
+	require.Equal(t, 3, len(list[0].Attempts))
+	require.Equal(t, "not yet", list[0].Attempts[0].Error)
+}
+
+type jobFollower interface {
+	FollowJob(ctx context.Context, username, name string, cb func(line string) bool) error
+}
+
+func TestFollowJob(t *testing.T) {
+
+	service, done := newJobService(t, nil)
+	defer done()
+
+	follower, ok := service.(jobFollower)
+	require.True(t, ok)
+
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "report",
+		ExecutionFn: func(ctx context.Context) error {
+			sprintcore.JobOutput(ctx, "first")
+			sprintcore.JobOutputf(ctx, "second %d", 2)
+			return nil
+		},
+	}))
+
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "broken",
+		ExecutionFn: func(ctx context.Context) error {
+			sprintcore.JobOutput(ctx, "failing")
+			return errors.New("broken")
+		},
+	}))
+
+	var lines []string
+	err := follower.FollowJob(context.Background(), "admin", "report", func(line string) bool {
+		lines = append(lines, line)
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second 2"}, lines)
+
+	lines = nil
+	err = follower.FollowJob(context.Background(), "admin", "broken", func(line string) bool {
+		lines = append(lines, line)
+		return true
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"failing"}, lines)
+
+	require.Error(t, follower.FollowJob(context.Background(), "admin", "unknown", func(line string) bool {
+		return true
+	}))
+}
+
+func TestFollowJobDisconnect(t *testing.T) {
+
+	service, done := newJobService(t, nil)
+	defer done()
+
+	follower, ok := service.(jobFollower)
+	require.True(t, ok)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	require.NoError(t, service.AddJob(&sprint.JobInfo{
+		Name: "long",
+		ExecutionFn: func(ctx context.Context) error {
+			defer close(finished)
+			sprintcore.JobOutput(ctx, "started")
+			<-release
+			return nil
+		},
+	}))
+
+	err := follower.FollowJob(context.Background(), "admin", "long", func(line string) bool {
+		return false
+	})
+	require.Error(t, err)
+
+	// the job keeps running after the follower disconnects
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "job did not finish")
+	}
+
+	require.Eventually(t, func() bool {
+		list := getJobHistory(t, service, "long")
+		return len(list) == 1 && list[0].Status == sprintcore.JobSucceeded
+	}, 5 * time.Second, 10 * time.Millisecond)
+}
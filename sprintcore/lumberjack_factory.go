@@ -14,8 +14,16 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
 )
 
+/**
+	Guards MaxSize of the lumberjack loggers, that is read by Logger.Write, against changes at runtime.
+	Writes through lumberjackWriter take the read lock, so writers do not block each other.
+ */
+
+var lumberjackMu sync.RWMutex
+
 type implLumberjackFactory struct {
 	Application      sprint.Application       `inject`
 	ApplicationFlags sprint.ApplicationFlags  `inject`
@@ -30,6 +38,8 @@ type implLumberjackFactory struct {
 	MaxAge      int   `value:"lumberjack.max-age,default=28"` // days
 	Compress    bool  `value:"lumberjack.compress,default=false"` // disabled by default
 	Rotate      bool  `value:"lumberjack.rotate-on-start,default=false"` // disabled by default
+
+	instance    *lumberjack.Logger
 }
 
 func LumberjackFactory() glue.FactoryBean {
@@ -75,10 +85,29 @@ func (t *implLumberjackFactory) Object() (object interface{}, err error) {
 		}
 	}
 
+	t.instance = instance
 	return instance, nil
 
 }
 
+func (t *implLumberjackFactory) ReloadPrefixes() []string {
+	return []string{ "lumberjack.max-size" }
+}
+
+/**
+	Max size is applied on the next write, other limits are read by the background goroutine of the logger and need restart.
+ */
+
+func (t *implLumberjackFactory) Reload(key, value string) error {
+	if t.instance == nil {
+		return nil
+	}
+	lumberjackMu.Lock()
+	defer lumberjackMu.Unlock()
+	t.instance.MaxSize = t.Properties.GetInt(key, 500)
+	return nil
+}
+
 func (t *implLumberjackFactory) ObjectType() reflect.Type {
 	return sprint.LumberjackClass
 }
@@ -94,3 +123,17 @@ func (t *implLumberjackFactory) Singleton() bool {
 func (t *implLumberjackFactory) getNodeName() string {
 	return sprintutils.AppendNodeSequence(t.Application.Name(), t.ApplicationFlags.Node())
 }
+
+/**
+	Writes to the lumberjack logger consistently with the reload of its max size.
+ */
+
+type lumberjackWriter struct {
+	logger  *lumberjack.Logger
+}
+
+func (w lumberjackWriter) Write(p []byte) (int, error) {
+	lumberjackMu.RLock()
+	defer lumberjackMu.RUnlock()
+	return w.logger.Write(p)
+}
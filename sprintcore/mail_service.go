@@ -12,6 +12,7 @@ import (
 	"github.com/mailgun/mailgun-go/v4"
 	"github.com/codeallergy/glue"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintutils"
 	"go.uber.org/zap"
 	"strings"
 	"time"
@@ -21,6 +22,7 @@ type implMailService struct {
 	Properties      glue.Properties     `inject`
 	ResourceService sprint.ResourceService `inject`
 	Log             *zap.Logger           `inject`
+	RateLimiter     *sprintutils.RateLimiter  `inject:"bean=mailgun,optional"`
 }
 
 func MailService() sprint.MailService {
@@ -81,7 +83,7 @@ func (t *implMailService) SendMail(mail *sprint.Mail, timeout time.Duration, asy
 
 	}
 
-	sendFn := func() error {
+	doSend := func() error {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
@@ -95,6 +97,13 @@ func (t *implMailService) SendMail(mail *sprint.Mail, timeout time.Duration, asy
 		return err
 	}
 
+	sendFn := doSend
+	if t.RateLimiter != nil {
+		sendFn = func() error {
+			return t.RateLimiter.Do(doSend)
+		}
+	}
+
 	if async {
 		go sendFn()
 		return nil
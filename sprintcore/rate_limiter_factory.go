@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore
+
+import (
+	"fmt"
+	"github.com/codeallergy/glue"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"reflect"
+	"time"
+)
+
+var (
+	RateLimiterClass = reflect.TypeOf((*sprintutils.RateLimiter)(nil))
+
+	DefaultRateLimit = 100 * time.Millisecond
+)
+
+/**
+	Creates the named rate limiter with the minimum interval between requests from '<beanName>.rate-limit' property.
+	The interval is applied without restart by PropertyReloader of the context.
+	Core services have 'mailgun' rate limiter that throttles SendMail.
+ */
+
+type implRateLimiterFactory struct {
+	Properties  glue.Properties  `inject`
+
+	beanName  string
+	instance  *sprintutils.RateLimiter
+}
+
+func RateLimiterFactory(beanName string) glue.FactoryBean {
+	return &implRateLimiterFactory{beanName: beanName}
+}
+
+func (t *implRateLimiterFactory) limitKey() string {
+	return fmt.Sprintf("%s.rate-limit", t.beanName)
+}
+
+func (t *implRateLimiterFactory) Object() (object interface{}, err error) {
+	t.instance = &sprintutils.RateLimiter{
+		Limit: t.Properties.GetDuration(t.limitKey(), DefaultRateLimit),
+	}
+	return t.instance, nil
+}
+
+func (t *implRateLimiterFactory) ReloadPrefixes() []string {
+	return []string{ t.limitKey() }
+}
+
+func (t *implRateLimiterFactory) Reload(key, value string) error {
+	if t.instance == nil || key != t.limitKey() {
+		return nil
+	}
+	t.instance.SetLimit(t.Properties.GetDuration(key, DefaultRateLimit))
+	return nil
+}
+
+func (t *implRateLimiterFactory) ObjectType() reflect.Type {
+	return RateLimiterClass
+}
+
+func (t *implRateLimiterFactory) ObjectName() string {
+	return t.beanName
+}
+
+func (t *implRateLimiterFactory) Singleton() bool {
+	return true
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"github.com/codeallergy/glue"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type rateLimiterHolder struct {
+	Limiter *sprintutils.RateLimiter `inject`
+}
+
+func TestRateLimiterReload(t *testing.T) {
+
+	resolver := &mapPropertyResolver{values: map[string]string{"mailgun.rate-limit": "1s"}}
+	holder := new(rateLimiterHolder)
+
+	ctx, err := glue.New(resolver, sprintcore.RateLimiterFactory("mailgun"), holder)
+	require.NoError(t, err)
+	defer ctx.Close()
+
+	require.Equal(t, time.Second, holder.Limiter.GetLimit())
+
+	list := ctx.Bean(sprintapp.ReloadableBeanClass, glue.DefaultLevel)
+	require.Equal(t, 1, len(list))
+	bean := list[0].Object().(sprintapp.ReloadableBean)
+	require.Equal(t, []string{"mailgun.rate-limit"}, bean.ReloadPrefixes())
+
+	resolver.set("mailgun.rate-limit", "5ms")
+	require.NoError(t, bean.Reload("mailgun.rate-limit", "5ms"))
+	require.Equal(t, 5*time.Millisecond, holder.Limiter.GetLimit())
+}
@@ -5,12 +5,18 @@
 
 package sprintcore
 
+import "github.com/sprintframework/sprintframework/sprintapp"
+
 var CoreServices = []interface{} {
 	ZapLogFactory(),
 	HCLogFactory(),
 	NodeService(),
 	ConfigRepository(10000),
+	StoreKeyRegistry(),
+	sprintapp.DefaultPropertySchema(),
+	sprintapp.PropertyReloader(),
 	JobService(),
 	StorageService(),
 	MailService(),
+	RateLimiterFactory("mailgun"),
 }
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/keyvalstore/store"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	RevokedBucket    = "revoked"
+	RevokedBucketLen = len(RevokedBucket)
+)
+
+/**
+	Revocation list that keeps revoked tokens in the 'config-store' until they expire, revocations with unknown expiration are kept forever.
+	Revocations are shared between nodes only if the 'config-store' bean is backed by the shared storage.
+	IsRevoked checks the in-memory copy of the list, revocations of other nodes are loaded from the store each refresh interval.
+ */
+
+type implStoreTokenRevocationList struct {
+	Application  sprint.Application `inject`
+	Store        store.DataStore    `inject:"bean=config-store"`
+	Log          *zap.Logger        `inject`
+
+	GCInterval       time.Duration  `value:"jwt.revocation.gc-interval,default=1h"`
+	RefreshInterval  time.Duration  `value:"jwt.revocation.refresh-interval,default=10s"`
+
+	mu       sync.RWMutex
+	revoked  map[string]int64  // id, expiresAt of revoked tokens
+	pending  map[string]int64  // revocations of this node during the refresh, nil if no refresh is running
+}
+
+func StoreTokenRevocationList() sprintapp.TokenRevocationList {
+	return &implStoreTokenRevocationList{}
+}
+
+func (t *implStoreTokenRevocationList) BeanName() string {
+	return "token_revocation_list"
+}
+
+func (t *implStoreTokenRevocationList) GetStats(cb func(name, value string) bool) error {
+	list, err := t.List()
+	if err != nil {
+		return err
+	}
+	cb("revoked", strconv.Itoa(len(list)))
+	cb("gcInterval", t.GCInterval.String())
+	cb("refreshInterval", t.RefreshInterval.String())
+	return nil
+}
+
+func (t *implStoreTokenRevocationList) PostConstruct() error {
+	t.revoked = make(map[string]int64)
+	if err := t.Refresh(); err != nil {
+		return err
+	}
+	if t.GCInterval > 0 {
+		go t.loop(t.GCInterval, "RevocationGC", func() {
+			if n, err := t.PurgeExpired(); err != nil {
+				t.Log.Error("RevocationGC", zap.Error(err))
+			} else if n > 0 {
+				t.Log.Info("RevocationGC", zap.Int("removed", n))
+			}
+		})
+	}
+	if t.RefreshInterval > 0 {
+		go t.loop(t.RefreshInterval, "RevocationRefresh", func() {
+			if err := t.Refresh(); err != nil {
+				t.Log.Error("RevocationRefresh", zap.Error(err))
+			}
+		})
+	}
+	return nil
+}
+
+func (t *implStoreTokenRevocationList) loop(interval time.Duration, name string, fn func()) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Log.Error("Recover" + name, zap.String("err", fmt.Sprintf("%v", r)))
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fn()
+		case <-t.Application.Done():
+			return
+		}
+	}
+}
+
+/**
+	Reloads the in-memory copy of the list from the store, keeps revocations made by this node during the reload.
+ */
+
+func (t *implStoreTokenRevocationList) Refresh() error {
+
+	t.mu.Lock()
+	t.pending = make(map[string]int64)
+	t.mu.Unlock()
+
+	loaded := make(map[string]int64)
+	now := time.Now().Unix()
+	err := t.enumerate(func(id string, r *sprintapp.RevokedToken) {
+		if r.ExpiresAt == 0 || r.ExpiresAt > now {
+			loaded[id] = r.ExpiresAt
+		}
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, expiresAt := range t.pending {
+		loaded[id] = expiresAt
+	}
+	t.pending = nil
+	if err != nil {
+		return err
+	}
+	t.revoked = loaded
+	return nil
+}
+
+func (t *implStoreTokenRevocationList) Revoke(id string, expiresAt int64, username string) error {
+
+	value, err := json.Marshal(&sprintapp.RevokedToken{
+		Id:        id,
+		User:      username,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := t.Store.Set(context.Background()).ByKey("%s:%s", RevokedBucket, id).Binary(value); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.revoked[id] = expiresAt
+	if t.pending != nil {
+		t.pending[id] = expiresAt
+	}
+	t.mu.Unlock()
+
+	t.Log.Info("TokenRevoked", zap.String("id", id), zap.String("user", username), zap.Int64("expiresAt", expiresAt))
+	return nil
+}
+
+func (t *implStoreTokenRevocationList) IsRevoked(id string) (bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.revoked[id]
+	return ok, nil
+}
+
+func (t *implStoreTokenRevocationList) List() ([]*sprintapp.RevokedToken, error) {
+	var list []*sprintapp.RevokedToken
+	now := time.Now().Unix()
+	err := t.enumerate(func(id string, r *sprintapp.RevokedToken) {
+		if r.ExpiresAt == 0 || r.ExpiresAt > now {
+			list = append(list, r)
+		}
+	})
+	return list, err
+}
+
+func (t *implStoreTokenRevocationList) PurgeExpired() (int, error) {
+
+	var expired []string
+	now := time.Now().Unix()
+	err := t.enumerate(func(id string, r *sprintapp.RevokedToken) {
+		if r.ExpiresAt != 0 && r.ExpiresAt <= now {
+			expired = append(expired, id)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range expired {
+		if err := t.Store.Remove(context.Background()).ByKey("%s:%s", RevokedBucket, id).Do(); err != nil {
+			return 0, err
+		}
+		t.mu.Lock()
+		delete(t.revoked, id)
+		t.mu.Unlock()
+	}
+	return len(expired), nil
+}
+
+func (t *implStoreTokenRevocationList) enumerate(cb func(id string, r *sprintapp.RevokedToken)) error {
+	return t.Store.
+		Enumerate(context.Background()).
+		ByPrefix("%s:", RevokedBucket).
+		WithBatchSize(256).
+		Do(func(entry *store.RawEntry) bool {
+			id := string(entry.Key[RevokedBucketLen+1:])
+			r := new(sprintapp.RevokedToken)
+			if err := json.Unmarshal(entry.Value, r); err != nil {
+				// broken record, remove by GC
+				r.Id = id
+				r.ExpiresAt = -1
+			}
+			cb(id, r)
+			return true
+		})
+}
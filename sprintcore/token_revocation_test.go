@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/keyvalstore/store"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type revocationListHolder struct {
+	List   sprintapp.TokenRevocationList `inject`
+	Store  store.DataStore               `inject:"bean=config-store"`
+}
+
+func newRevocationList(t *testing.T) (*revocationListHolder, func()) {
+
+	holder := new(revocationListHolder)
+	_, done := newStoreContext(t,
+		sprintapp.Application("test"),
+		&mapPropertyResolver{values: map[string]string{
+			"jwt.revocation.gc-interval":      "0",
+			"jwt.revocation.refresh-interval": "0",
+		}},
+		zap.NewNop(),
+		sprintcore.StoreTokenRevocationList(),
+		holder,
+	)
+
+	return holder, done
+}
+
+func TestTokenRevocation(t *testing.T) {
+
+	holder, done := newRevocationList(t)
+	defer done()
+	list := holder.List
+
+	revoked, err := list.IsRevoked("id1")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, list.Revoke("id1", time.Now().Add(time.Hour).Unix(), "admin"))
+	require.NoError(t, list.Revoke("id2", 0, "admin"))
+
+	revoked, err = list.IsRevoked("id1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = list.IsRevoked("id2")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	tokens, err := list.List()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tokens))
+	for _, r := range tokens {
+		require.Equal(t, "admin", r.User)
+	}
+}
+
+func TestTokenRevocationPurgeExpired(t *testing.T) {
+
+	holder, done := newRevocationList(t)
+	defer done()
+	list := holder.List
+
+	require.NoError(t, list.Revoke("expired", time.Now().Add(-time.Minute).Unix(), "admin"))
+	require.NoError(t, list.Revoke("never", 0, "admin"))
+	require.NoError(t, list.Revoke("later", time.Now().Add(time.Hour).Unix(), "admin"))
+
+	tokens, err := list.List()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tokens))
+
+	n, err := list.PurgeExpired()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	revoked, err := list.IsRevoked("expired")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	for _, id := range []string{"never", "later"} {
+		revoked, err = list.IsRevoked(id)
+		require.NoError(t, err)
+		require.True(t, revoked, id)
+	}
+
+	n, err = list.PurgeExpired()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestTokenRevocationRefresh(t *testing.T) {
+
+	holder, done := newRevocationList(t)
+	defer done()
+
+	refresher, ok := holder.List.(interface{ Refresh() error })
+	require.True(t, ok)
+
+	// revocation of the other node sharing the store
+	value, err := json.Marshal(&sprintapp.RevokedToken{Id: "remote", User: "admin", RevokedAt: time.Now().Unix()})
+	require.NoError(t, err)
+	require.NoError(t, holder.Store.Set(context.Background()).ByKey("%s:%s", sprintcore.RevokedBucket, "remote").Binary(value))
+
+	revoked, err := holder.List.IsRevoked("remote")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, holder.List.Revoke("local", 0, "admin"))
+	require.NoError(t, refresher.Refresh())
+
+	for _, id := range []string{"remote", "local"} {
+		revoked, err = holder.List.IsRevoked(id)
+		require.NoError(t, err)
+		require.True(t, revoked, id)
+	}
+}
+
+func TestRevokeAuthToken(t *testing.T) {
+
+	holder, done := newRevocationList(t)
+	defer done()
+
+	_, err := sprintapp.RevokeAuthToken(holder.List, "  ", "admin")
+	require.Error(t, err)
+
+	id, err := sprintapp.RevokeAuthToken(holder.List, " raw-id ", "admin")
+	require.NoError(t, err)
+	require.Equal(t, "raw-id", id)
+
+	expiresAt := time.Now().Unix() + 3600
+	token, err := sprintutils.GenerateAuthToken(sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("secret"))), "jwt-id", &sprint.AuthorizedUser{
+		Username:  "user",
+		ExpiresAt: expiresAt,
+	}, nil)
+	require.NoError(t, err)
+
+	id, err = sprintapp.RevokeAuthToken(holder.List, token, "admin")
+	require.NoError(t, err)
+	require.Equal(t, "jwt-id", id)
+
+	expires := make(map[string]int64)
+	tokens, err := holder.List.List()
+	require.NoError(t, err)
+	for _, r := range tokens {
+		expires[r.Id] = r.ExpiresAt
+	}
+	require.Equal(t, map[string]int64{"raw-id": 0, "jwt-id": expiresAt}, expires)
+}
@@ -8,6 +8,7 @@ package sprintcore
 import (
 	"fmt"
 	"github.com/codeallergy/glue"
+	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"go.uber.org/zap"
@@ -28,7 +29,9 @@ type implZapLogFactory struct {
 	LogDir         string        `value:"application.log.dir,default="`
 	LogDirPerm     os.FileMode   `value:"application.perm.log.dir,default=-rwxrwxr-x"`
 	LogFilePerm    os.FileMode   `value:"application.perm.log.file,default=-rw-rw-r--"`
+	LogLevel       string        `value:"application.log.level,default=debug"`
 
+	level          zap.AtomicLevel
 }
 
 func ZapLogFactory() glue.FactoryBean {
@@ -39,18 +42,23 @@ func (t *implZapLogFactory) Object() (object interface{}, err error) {
 
 	defer sprintutils.PanicToError(&err)
 
+	t.level = zap.NewAtomicLevel()
+	if err := t.level.UnmarshalText([]byte(t.LogLevel)); err != nil {
+		return nil, errors.Errorf("invalid property 'application.log.level', %v", err)
+	}
+
 	if t.ApplicationFlags.Daemon() {
 
 		if t.RotateLogger != nil {
 
-			writerSyncer := zapcore.AddSync(t.RotateLogger)
+			writerSyncer := zapcore.AddSync(lumberjackWriter{t.RotateLogger})
 
 			encoderConfig := zap.NewProductionEncoderConfig()
 			encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 			encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 			encoder := zapcore.NewConsoleEncoder(encoderConfig)
 
-			core := zapcore.NewCore(encoder, writerSyncer, zapcore.DebugLevel)
+			core := zapcore.NewCore(encoder, writerSyncer, t.level)
 
 			return zap.New(core, zap.AddCaller()), nil
 
@@ -78,6 +86,7 @@ func (t *implZapLogFactory) Object() (object interface{}, err error) {
 			}
 
 			cfg := zap.NewDevelopmentConfig()
+			cfg.Level = t.level
 			cfg.OutputPaths = []string{
 				logFile,
 			}
@@ -85,11 +94,22 @@ func (t *implZapLogFactory) Object() (object interface{}, err error) {
 		}
 
 	} else {
-		return zap.NewDevelopment()
+		cfg := zap.NewDevelopmentConfig()
+		cfg.Level = t.level
+		return cfg.Build()
 	}
 
 }
 
+func (t *implZapLogFactory) ReloadPrefixes() []string {
+	return []string{ "application.log.level" }
+}
+
+func (t *implZapLogFactory) Reload(key, value string) error {
+	level := t.Properties.GetString(key, "debug")
+	return t.level.UnmarshalText([]byte(level))
+}
+
 func (t *implZapLogFactory) ObjectType() reflect.Type {
 	return sprint.ZapLogClass
 }
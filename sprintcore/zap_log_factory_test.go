@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintcore_test
+
+import (
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintcore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+type zapLogHolder struct {
+	Log        *zap.Logger             `inject`
+	Repository sprint.ConfigRepository `inject`
+}
+
+func TestZapLogLevelReload(t *testing.T) {
+
+	holder := new(zapLogHolder)
+	_, done := newStoreContext(t,
+		sprintapp.Application("test"),
+		sprintapp.ApplicationFlags(0),
+		sprintcore.ZapLogFactory(),
+		sprintcore.ConfigRepository(100),
+		sprintapp.PropertyReloader(),
+		holder,
+	)
+	defer done()
+
+	require.True(t, holder.Log.Core().Enabled(zapcore.DebugLevel))
+
+	require.NoError(t, holder.Repository.Set("application.log.level", "error"))
+
+	require.Eventually(t, func() bool {
+		return !holder.Log.Core().Enabled(zapcore.WarnLevel)
+	}, 5 * time.Second, 10 * time.Millisecond)
+	require.True(t, holder.Log.Core().Enabled(zapcore.ErrorLevel))
+}
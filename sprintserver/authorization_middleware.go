@@ -7,13 +7,15 @@ package sprintserver
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"github.com/codeallergy/glue"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"os/user"
 	"strings"
 	"sync"
@@ -26,11 +28,16 @@ type implAuthorizationMiddleware struct {
 	Application      sprint.Application      `inject`
 	Properties       glue.Properties         `inject`
 	ConfigRepository sprint.ConfigRepository `inject`
+	NodeService      sprint.NodeService      `inject`
 	Log              *zap.Logger             `inject`
 
-	invalidTokens sync.Map // key is string, value is true
+	RevocationList   sprintapp.TokenRevocationList `inject:"optional"`
 
-	secretKey []byte // JWT tokens secret key
+	invalidTokens sync.Map // key is JWT ID, value is true, used without revocation list
+
+	mu        sync.RWMutex
+	keyring   *sprintutils.AuthKeyring // JWT signing and verification keys
+	policy    *sprintutils.AuthTokenPolicy
 }
 
 func AuthorizationMiddleware() sprint.AuthorizationMiddleware {
@@ -39,38 +46,86 @@ func AuthorizationMiddleware() sprint.AuthorizationMiddleware {
 
 func (t *implAuthorizationMiddleware) PostConstruct() (err error) {
 
-	secret := t.Properties.GetString("jwt.secret.key", "")
+	secret := t.Properties.GetString(sprintapp.JwtSecretKeyProperty, "")
 
-	if secret == "" {
+	if secret == "" && t.Properties.GetString(sprintapp.JwtKeyringActive, "") == "" {
 		secret, err = sprintutils.GenerateToken()
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("Generated JWT 'jwt.secret.key' property: %s\n", secret)
-		err = t.ConfigRepository.Set("jwt.secret.key", secret)
+		err = t.ConfigRepository.Set(sprintapp.JwtSecretKeyProperty, secret)
 		if err != nil {
 			return err
 		}
 
-		authToken, err := t.generateDefaultAuthToken(secret)
+		if err := t.loadKeyring(); err != nil {
+			return err
+		}
+
+		authToken, err := t.generateDefaultAuthToken()
 		if err != nil {
 			return err
 		}
 		fmt.Printf("export %s_AUTH=%s\n", strings.ToUpper(t.Application.Name()), authToken)
 	}
 
-	t.secretKey, err = base64.RawURLEncoding.DecodeString(secret)
-	return err
+	return t.loadKeyring()
 }
 
-func (t *implAuthorizationMiddleware) generateDefaultAuthToken(secret string) (string, error) {
+func (t *implAuthorizationMiddleware) loadKeyring() error {
 
-	secretKey, err := base64.RawURLEncoding.DecodeString(secret)
+	keyring, err := sprintapp.LoadAuthKeyring(t.Properties)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if _, _, err := keyring.SigningKey(); err != nil {
+		return err
 	}
 
+	policy := sprintapp.AuthTokenPolicy(t.Application.Name(), t.Properties)
+	if warning := sprintapp.LegacyAuthTokensWarning(t.Properties); warning != "" {
+		t.Log.Warn("LegacyAuthTokens", zap.String("warning", warning))
+	}
+
+	t.mu.Lock()
+	t.keyring = keyring
+	t.policy = policy
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *implAuthorizationMiddleware) getKeyring() (*sprintutils.AuthKeyring, *sprintutils.AuthTokenPolicy) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.keyring, t.policy
+}
+
+/**
+	Public keys of the keyring for services that verify tokens without the private key.
+ */
+
+func (t *implAuthorizationMiddleware) JWKS() ([]byte, error) {
+	keyring, _ := t.getKeyring()
+	return keyring.JWKS()
+}
+
+func (t *implAuthorizationMiddleware) ReloadPrefixes() []string {
+	return []string{"jwt."}
+}
+
+/**
+	Rotated keys are applied without restart, the previous keyring stays if the new one has no signing key.
+ */
+
+func (t *implAuthorizationMiddleware) Reload(key, value string) error {
+	return t.loadKeyring()
+}
+
+func (t *implAuthorizationMiddleware) generateDefaultAuthToken() (string, error) {
+
 	user, err := user.Current()
 	if err != nil {
 		return "", err
@@ -86,13 +141,19 @@ func (t *implAuthorizationMiddleware) generateDefaultAuthToken(secret string) (s
 		ExpiresAt: time.Now().Unix() + 356*24*3600,
 	}
 
-	return sprintutils.GenerateAuthToken(secretKey, u)
+	return t.GenerateToken(u)
 }
 
 func (t *implAuthorizationMiddleware) Authenticate(ctx context.Context) (outCtx context.Context, err error) {
 
-	user, ok := t.doAuthenticate(ctx)
-	if !ok {
+	user, err := t.doAuthenticate(ctx)
+	if err != nil {
+		// the client gets the kind of the failure only
+		t.Log.Debug("Authenticate", zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, sprintutils.AuthTokenErrorMessage(err))
+	}
+
+	if user == nil {
 
 		user = &sprint.AuthorizedUser{
 			Username:  "",
@@ -107,48 +168,86 @@ func (t *implAuthorizationMiddleware) Authenticate(ctx context.Context) (outCtx
 
 }
 
-func (t *implAuthorizationMiddleware) doAuthenticate(ctx context.Context) (*sprint.AuthorizedUser, bool) {
+/**
+	Returns nil user without error for requests without token.
+ */
+
+func (t *implAuthorizationMiddleware) doAuthenticate(ctx context.Context) (*sprint.AuthorizedUser, error) {
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, false
+		return nil, nil
 	}
 
 	authHeaders, ok := md["authorization"]
 	if !ok {
-		return nil, false
+		return nil, nil
 	}
 
 	if len(authHeaders) != 1 {
-		return nil, false
+		return nil, nil
 	}
 
 	auth := authHeaders[0]
-	return t.AuthenticateByHeader(auth)
+	return t.authenticateHeader(auth)
 }
 
 func (t *implAuthorizationMiddleware) AuthenticateByHeader(authHeader string) (*sprint.AuthorizedUser, bool) {
+	user, err := t.authenticateHeader(authHeader)
+	return user, err == nil && user != nil
+}
+
+func (t *implAuthorizationMiddleware) authenticateHeader(authHeader string) (*sprint.AuthorizedUser, error) {
 
 	const prefix = "Bearer "
 	if !strings.HasPrefix(authHeader, prefix) {
-		return nil, false
+		return nil, nil
 	}
 
 	token := strings.TrimSpace(strings.TrimPrefix(authHeader, prefix))
 	if token == "" {
-		return nil, false
+		return nil, &sprintutils.AuthTokenError{Kind: sprintutils.ErrTokenMalformed, Reason: "empty bearer token"}
+	}
+
+	user, err := t.ParseToken(token)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, ok := t.invalidTokens.Load(token); ok {
-		return nil, false
+	if err := t.checkRevoked(token); err != nil {
+		return nil, err
 	}
 
-	user, err := sprintutils.VerifyAuthToken(t.secretKey, token)
+	return user, nil
+}
+
+/**
+	Fails closed, the token is not accepted if the revocation list is not available.
+ */
+
+func (t *implAuthorizationMiddleware) checkRevoked(token string) error {
+
+	id, _, err := sprintutils.ParseAuthTokenId(token)
 	if err != nil {
-		return nil, false
+		return err
+	}
+
+	if _, ok := t.invalidTokens.Load(id); ok {
+		return &sprintutils.AuthTokenError{Kind: sprintutils.ErrTokenRevoked, Reason: id}
 	}
 
-	return user, true
+	if t.RevocationList != nil {
+		revoked, err := t.RevocationList.IsRevoked(id)
+		if err != nil {
+			t.Log.Error("TokenRevocationCheck", zap.String("id", id), zap.Error(err))
+			return &sprintutils.AuthTokenError{Kind: sprintutils.ErrTokenRevoked, Reason: "revocation list is not available"}
+		}
+		if revoked {
+			return &sprintutils.AuthTokenError{Kind: sprintutils.ErrTokenRevoked, Reason: id}
+		}
+	}
+
+	return nil
 }
 
 func (t *implAuthorizationMiddleware) GetUser(ctx context.Context) (*sprint.AuthorizedUser, bool) {
@@ -161,7 +260,8 @@ func (t *implAuthorizationMiddleware) GetUser(ctx context.Context) (*sprint.Auth
 		}
 	} else {
 		// Middleware could miss the request
-		return t.doAuthenticate(ctx)
+		user, err := t.doAuthenticate(ctx)
+		return user, err == nil && user != nil
 	}
 }
 
@@ -183,13 +283,36 @@ func (t *implAuthorizationMiddleware) UserContext(ctx context.Context, name stri
 }
 
 func (t *implAuthorizationMiddleware) GenerateToken(user *sprint.AuthorizedUser) (string, error) {
-	return sprintutils.GenerateAuthToken(t.secretKey, user)
+	keyring, policy := t.getKeyring()
+	return sprintutils.GenerateAuthToken(keyring, t.NodeService.Issue().String(), user, policy)
 }
 
 func (t *implAuthorizationMiddleware) ParseToken(token string) (*sprint.AuthorizedUser, error) {
-	return sprintutils.VerifyAuthToken(t.secretKey, token)
+	keyring, policy := t.getKeyring()
+	return sprintutils.VerifyAuthToken(keyring, token, policy)
 }
 
 func (t *implAuthorizationMiddleware) InvalidateToken(token string) {
-	t.invalidTokens.Store(token, true)
+	if _, err := t.RevokeToken(token, ""); err != nil {
+		t.Log.Error("InvalidateToken", zap.Error(err))
+	}
+}
+
+/**
+	Revokes the token or JWT ID, the revocation survives restarts if the revocation list is available.
+ */
+
+func (t *implAuthorizationMiddleware) RevokeToken(tokenOrId, username string) (string, error) {
+	if t.RevocationList != nil {
+		return sprintapp.RevokeAuthToken(t.RevocationList, tokenOrId, username)
+	}
+	id := tokenOrId
+	if sprintutils.IsJwtToken(tokenOrId) {
+		var err error
+		if id, _, err = sprintutils.ParseAuthTokenId(tokenOrId); err != nil {
+			return "", err
+		}
+	}
+	t.invalidTokens.Store(id, true)
+	return id, nil
 }
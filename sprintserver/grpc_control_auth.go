@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintserver
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/sprintframework/sprintpb"
+	"go.uber.org/zap"
+)
+
+/**
+	Optional extension of the authorization middleware that revokes tokens by JWT ID.
+ */
+
+type tokenRevoker interface {
+	RevokeToken(tokenOrId, username string) (string, error)
+}
+
+func (t *implGrpcControlServer) Auth(ctx context.Context, req *sprintpb.Command) (resp *sprintpb.CommandResult, err error) {
+
+	defer sprintutils.PanicToError(&err)
+
+	user, ok := t.AuthorizationMiddleware.GetUser(ctx)
+	if !ok {
+		return nil, ErrAuthUserNotFound
+	}
+
+	if user.Roles == nil || !user.Roles["ADMIN"] {
+		return nil, ErrAuthWrongRole
+	}
+
+	switch req.Command {
+	case "revoke":
+		return t.authRevoke(req.Args, user.Username)
+	case "revoked":
+		return t.authRevoked()
+	case "rotate-jwt":
+		return t.authRotateJwt(req.Args, user.Username)
+	case "retire-jwt":
+		return t.authRetireJwt(req.Args, user.Username)
+	default:
+		return nil, errors.Errorf("unknown command '%s'", req.Command)
+	}
+}
+
+func (t *implGrpcControlServer) authRevoke(args []string, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("auth revoke command needs token or JWT ID argument")
+	}
+
+	revoker, ok := t.AuthorizationMiddleware.(tokenRevoker)
+	if !ok {
+		return nil, errors.New("authorization middleware does not support revocation")
+	}
+
+	id, err := revoker.RevokeToken(args[0], username)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Log.Info("AuthRevoke", zap.String("id", id), zap.String("user", username))
+
+	return &sprintpb.CommandResult{Content: id}, nil
+}
+
+func (t *implGrpcControlServer) authRevoked() (resp *sprintpb.CommandResult, err error) {
+
+	if t.RevocationList == nil {
+		return nil, errors.New("revocation list is not available")
+	}
+
+	list, err := t.RevocationList.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return &sprintpb.CommandResult{Content: sprintapp.FormatRevokedTokens(list)}, nil
+}
+
+func (t *implGrpcControlServer) authRotateJwt(args []string, username string) (resp *sprintpb.CommandResult, err error) {
+
+	var alg string
+	if len(args) > 0 {
+		alg = args[0]
+	}
+
+	kid, err := sprintapp.RotateAuthKey(t.ConfigRepository, alg, username)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Log.Info("AuthRotateJwt", zap.String("kid", kid), zap.String("alg", alg), zap.String("user", username))
+
+	return &sprintpb.CommandResult{Content: kid}, nil
+}
+
+func (t *implGrpcControlServer) authRetireJwt(args []string, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("auth retire-jwt command needs key id argument")
+	}
+	kid := args[0]
+
+	if err := sprintapp.RetireAuthKey(t.ConfigRepository, kid, username); err != nil {
+		return nil, err
+	}
+
+	t.Log.Info("AuthRetireJwt", zap.String("kid", kid), zap.String("user", username))
+
+	return &sprintpb.CommandResult{Content: kid}, nil
+}
@@ -19,6 +19,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -42,6 +43,14 @@ var (
 	ErrTimeout     = errors.New("timeout")
 )
 
+/**
+	Optional extension of the service that records the user executing the command.
+ */
+
+type userCommandExecutor interface {
+	ExecuteUserCommand(username, cmd string, args []string) (string, error)
+}
+
 type implGrpcControlServer struct {
 	sprintpb.UnimplementedControlServiceServer
 
@@ -49,6 +58,7 @@ type implGrpcControlServer struct {
 	GatewayServer  *http.Server `inject:"bean=control-gateway-server,optional"`
 
 	Components  []sprint.Component   `inject:"optional,level=-1"`
+	PropertyResolvers  []glue.PropertyResolver  `inject:"optional,level=-1"`
 
 	Application         sprint.Application      `inject`
 	Properties          glue.Properties         `inject`
@@ -60,6 +70,7 @@ type implGrpcControlServer struct {
 	JobService            sprint.JobService            `inject`
 	StorageService        sprint.StorageService        `inject`
 	ConfigRepository      sprint.ConfigRepository      `inject`
+	RevocationList        sprintapp.TokenRevocationList  `inject:"optional"`
 	CertificateService    cert.CertificateService    `inject:"optional"`
 	CertificateManager    cert.CertificateManager    `inject:"optional"`
 
@@ -80,6 +91,7 @@ func (t *implGrpcControlServer) PostConstruct() (err error) {
 	defer sprintutils.PanicToError(&err)
 
 	sprintpb.RegisterControlServiceServer(t.GrpcServer, t)
+	t.GrpcServer.RegisterService(controlStreamServiceDesc(), t)
 	reflection.Register(t.GrpcServer)
 
 	if t.GatewayServer != nil {
@@ -203,23 +215,51 @@ func (t *implGrpcControlServer) Config(ctx context.Context, req *sprintpb.Comman
 	}
 	username := user.Username
 
+	args, reveal := sprintapp.ExtractRevealFlag(req.Args)
+	mask := sprintapp.ConfigMaskPolicy(t.Properties)
+	if reveal {
+		// reveal policy is nil
+		mask = nil
+	}
 
 	switch req.Command {
 	case "get":
-		return t.configGet(req.Args)
+		return t.configGet(args, mask, username)
 	case "set":
-		return t.configSet(req.Args, username)
-	case "dump":
-		return t.configDump(req.Args)
-	case "list":
-		return t.configList(req.Args)
+		return t.configSet(args, username)
+	case "dump", "list":
+		return t.configDump(req.Command, args, mask, username)
+	case "history":
+		return t.configHistory(args)
+	case "rollback":
+		return t.configRollback(args, username)
+	case "describe":
+		return t.configDescribe(args)
+	case "sources":
+		return t.configSources(args, mask, username)
+	case "export":
+		return t.configExport(args, mask, username)
+	case "import":
+		return t.configImport(args, username)
 	default:
 		return nil, errors.Errorf("unknown command '%s'", req.Command)
 	}
 
 }
 
-func (t *implGrpcControlServer) configGet(args []string) (resp *sprintpb.CommandResult, err error) {
+/**
+	Every reveal of sensitive values goes to the audit log.
+ */
+
+func (t *implGrpcControlServer) auditReveal(command, key, username string) {
+	t.Log.Warn("ConfigReveal", zap.String("command", command), zap.String("key", key), zap.String("user", username))
+}
+
+/**
+	Secrets are stored encrypted, the repository decrypts them, but the plain value is returned only on reveal.
+ */
+
+func (t *implGrpcControlServer) configGet(args []string, mask *sprintapp.MaskPolicy, username string) (resp *sprintpb.CommandResult, err error) {
 
 	if len(args) < 1 {
 		return nil, errors.New("config get command needs key argument")
@@ -232,8 +272,10 @@ func (t *implGrpcControlServer) configGet(args []string) (resp *sprintpb.Command
 		return nil, errors.Errorf("get config entry by key '%s', %v", key, err)
 	}
 
-	if sprintapp.IsHiddenProperty(key) {
-		value = "******"
+	if mask != nil {
+		value = mask.Mask(key, value)
+	} else {
+		t.auditReveal("get", key, username)
 	}
 
 	return &sprintpb.CommandResult{Content: value}, nil
@@ -248,7 +290,13 @@ func (t *implGrpcControlServer) configSet(args []string, username string) (resp
 	key := args[0]
 	value := args[1]
 
-	if err := t.ConfigRepository.Set(key, value); err != nil {
+	if versioned, ok := t.ConfigRepository.(sprintapp.VersionedConfigRepository); ok {
+		err = versioned.SetAs(key, value, username)
+	} else {
+		err = t.ConfigRepository.Set(key, value)
+	}
+	if err != nil {
+		err = sprintapp.ConfigMaskPolicy(t.Properties).MaskError(key, value, err)
 		return nil, errors.Errorf("set config entry by key '%s', %v", key, err)
 	}
 
@@ -257,31 +305,141 @@ func (t *implGrpcControlServer) configSet(args []string, username string) (resp
 	return &sprintpb.CommandResult{Content: "OK"}, nil
 }
 
-func (t *implGrpcControlServer) configDump(args []string) (resp *sprintpb.CommandResult, err error) {
+func (t *implGrpcControlServer) configHistory(args []string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("config history command needs key argument")
+	}
+
+	key := args[0]
+
+	versioned, ok := t.ConfigRepository.(sprintapp.VersionedConfigRepository)
+	if !ok {
+		return nil, errors.New("config repository does not keep history")
+	}
+
+	history, err := versioned.History(key)
+	if err != nil {
+		return nil, errors.Errorf("get config history by key '%s', %v", key, err)
+	}
+
+	return &sprintpb.CommandResult{Content: sprintapp.FormatConfigHistory(key, history, sprintapp.ConfigMaskPolicy(t.Properties))}, nil
+}
 
+func (t *implGrpcControlServer) configRollback(args []string, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 2 {
+		return nil, errors.New("config rollback command needs key and version arguments")
+	}
+
+	key := args[0]
+	version, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("parsing version '%s', %v", args[1], err)
+	}
+
+	versioned, ok := t.ConfigRepository.(sprintapp.VersionedConfigRepository)
+	if !ok {
+		return nil, errors.New("config repository does not keep history")
+	}
+
+	if err := versioned.Rollback(key, version, username); err != nil {
+		return nil, errors.Errorf("rollback config entry by key '%s' to version %d, %v", key, version, err)
+	}
+
+	t.Log.Info("ConfigRollback", zap.String("key", key), zap.Int64("version", version), zap.String("user", username))
+
+	return &sprintpb.CommandResult{Content: "OK"}, nil
+}
+
+func (t *implGrpcControlServer) configDescribe(args []string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("config describe command needs key argument")
+	}
+
+	key := args[0]
+
+	resolver, ok := t.ConfigRepository.(sprintapp.PropertySchemaResolver)
+	if !ok {
+		return nil, errors.New("config repository does not support schema")
+	}
+
+	def, ok := resolver.DescribeProperty(key)
+	if !ok {
+		return nil, errors.Errorf("schema not found for config entry '%s'", key)
+	}
+
+	return &sprintpb.CommandResult{Content: def.String()}, nil
+}
+
+func (t *implGrpcControlServer) configSources(args []string, mask *sprintapp.MaskPolicy, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("config sources command needs key argument")
+	}
+
+	key := args[0]
+
+	if mask == nil {
+		t.auditReveal("sources", key, username)
+	}
+
+	return &sprintpb.CommandResult{Content: sprintapp.PropertySources(key, t.Properties, t.PropertyResolvers, mask)}, nil
+}
+
+func (t *implGrpcControlServer) configExport(args []string, mask *sprintapp.MaskPolicy, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 1 {
+		return nil, errors.New("config export command needs format argument")
+	}
+
+	format := args[0]
 	var prefix string
-	if len(args) > 0 {
-		prefix = args[0]
+	if len(args) > 1 {
+		prefix = args[1]
 	}
 
-	var out strings.Builder
+	content, err := sprintapp.ExportConfig(t.ConfigRepository, prefix, format, mask)
+	if err != nil {
+		return nil, errors.Errorf("export config entries with prefix '%s', %v", prefix, err)
+	}
 
-	err = t.ConfigRepository.EnumerateAll(prefix, func(key, value string) bool {
-		if sprintapp.IsHiddenProperty(key) {
-			value = "******"
-		}
-		out.WriteString(key)
-		out.WriteString(": ")
-		out.WriteString(value)
-		out.WriteByte('\n')
-		return true
-	})
+	if mask == nil {
+		t.auditReveal("export", prefix, username)
+	}
 
-	return &sprintpb.CommandResult{Content: out.String()}, err
+	return &sprintpb.CommandResult{Content: string(content)}, nil
+}
+
+func (t *implGrpcControlServer) configImport(args []string, username string) (resp *sprintpb.CommandResult, err error) {
+
+	if len(args) < 4 {
+		return nil, errors.New("config import command needs format, mode, dry run and content arguments")
+	}
+
+	format, mode := args[0], args[1]
+	dryRun, err := strconv.ParseBool(args[2])
+	if err != nil {
+		return nil, errors.Errorf("parsing dry run flag '%s', %v", args[2], err)
+	}
+
+	entries, err := sprintapp.ParseConfig([]byte(args[3]), format)
+	if err != nil {
+		return nil, err
+	}
 
+	report, err := sprintapp.ImportConfig(t.ConfigRepository, entries, mode, dryRun, username)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Log.Info("ConfigImport", zap.String("mode", mode), zap.Bool("dryRun", dryRun), zap.Int("entries", len(entries)), zap.String("user", username))
+
+	return &sprintpb.CommandResult{Content: report}, nil
 }
 
-func (t *implGrpcControlServer) configList(args []string) (resp *sprintpb.CommandResult, err error) {
+func (t *implGrpcControlServer) configDump(cmd string, args []string, mask *sprintapp.MaskPolicy, username string) (resp *sprintpb.CommandResult, err error) {
 
 	var prefix string
 	if len(args) > 0 {
@@ -289,7 +447,11 @@ func (t *implGrpcControlServer) configList(args []string) (resp *sprintpb.Comman
 		args = args[1:]
 	}
 
-	limit := 80
+	limit := math.MaxInt64
+	if cmd == "list" {
+		limit = 80
+	}
+
 	if len(args) > 0 {
 		limit, err = strconv.Atoi(args[0])
 		if err != nil {
@@ -297,22 +459,12 @@ func (t *implGrpcControlServer) configList(args []string) (resp *sprintpb.Comman
 		}
 	}
 
-	var out strings.Builder
+	if mask == nil {
+		t.auditReveal(cmd, prefix, username)
+	}
 
-	err = t.ConfigRepository.EnumerateAll(prefix, func(key, value string) bool {
-		if sprintapp.IsHiddenProperty(key) {
-			value = "******"
-		}
-		if len(value) > limit {
-			value = value[:limit] + "..."
-			value = strings.ReplaceAll(value, "\n", " ")
-		}
-		out.WriteString(key)
-		out.WriteString(": ")
-		out.WriteString(value)
-		out.WriteByte('\n')
-		return true
-	})
+	var out strings.Builder
+	err = sprintapp.DumpConfig(t.ConfigRepository, prefix, limit, mask, &out)
 
 	return &sprintpb.CommandResult{Content: out.String()}, err
 
@@ -361,11 +513,21 @@ func (t *implGrpcControlServer) Job(ctx context.Context, req *sprintpb.Command)
 
 	defer sprintutils.PanicToError(&err)
 
-	if !t.AuthorizationMiddleware.HasUserRole(ctx, "ADMIN") {
-			return nil, ErrAuthAdminRequired
-		}
+	user, ok := t.AuthorizationMiddleware.GetUser(ctx)
+	if !ok {
+		return nil, ErrAuthUserNotFound
+	}
 
-	content, err := t.JobService.ExecuteCommand(req.Command, req.Args)
+	if user.Roles == nil || !user.Roles["ADMIN"] {
+		return nil, ErrAuthAdminRequired
+	}
+
+	var content string
+	if executor, ok := t.JobService.(userCommandExecutor); ok {
+		content, err = executor.ExecuteUserCommand(user.Username, req.Command, req.Args)
+	} else {
+		content, err = t.JobService.ExecuteCommand(req.Command, req.Args)
+	}
 	if err != nil {
 		return nil, err
 	}
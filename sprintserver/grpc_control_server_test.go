@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintserver
+
+import (
+	"context"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintpb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"strings"
+	"testing"
+)
+
+type mapConfigRepository struct {
+	sprint.ConfigRepository
+	entries map[string]string
+}
+
+func (t *mapConfigRepository) Get(key string) (string, error) {
+	return t.entries[key], nil
+}
+
+func (t *mapConfigRepository) EnumerateAll(prefix string, cb func(key, value string) bool) error {
+	for _, key := range []string{"application.name", "mail.smtp.password"} {
+		if value, ok := t.entries[key]; ok && strings.HasPrefix(key, prefix) {
+			cb(key, value)
+		}
+	}
+	return nil
+}
+
+func newConfigServer() (*implGrpcControlServer, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.WarnLevel)
+	return &implGrpcControlServer{
+		AuthorizationMiddleware: adminMiddleware{},
+		ConfigRepository:        &mapConfigRepository{entries: map[string]string{
+			"application.name":   "sprint",
+			"mail.smtp.password": "qwerty",
+		}},
+		Log: zap.New(core),
+	}, logs
+}
+
+func TestConfigMasked(t *testing.T) {
+
+	server, logs := newConfigServer()
+
+	resp, err := server.Config(context.Background(), &sprintpb.Command{Command: "get", Args: []string{"mail.smtp.password"}})
+	require.NoError(t, err)
+	require.Equal(t, sprintapp.MaskedValue, resp.Content)
+
+	resp, err = server.Config(context.Background(), &sprintpb.Command{Command: "dump"})
+	require.NoError(t, err)
+	require.Equal(t, "application.name: sprint\nmail.smtp.password: ******\n", resp.Content)
+
+	require.Equal(t, 0, logs.Len())
+}
+
+func TestConfigReveal(t *testing.T) {
+
+	server, logs := newConfigServer()
+
+	resp, err := server.Config(context.Background(), &sprintpb.Command{Command: "get", Args: []string{"mail.smtp.password", sprintapp.RevealFlag}})
+	require.NoError(t, err)
+	require.Equal(t, "qwerty", resp.Content)
+
+	resp, err = server.Config(context.Background(), &sprintpb.Command{Command: "dump", Args: []string{sprintapp.RevealFlag, "mail."}})
+	require.NoError(t, err)
+	require.Equal(t, "mail.smtp.password: qwerty\n", resp.Content)
+
+	// every reveal is audited
+	audit := logs.FilterMessage("ConfigReveal").AllUntimed()
+	require.Equal(t, 2, len(audit))
+	require.Equal(t, map[string]interface{}{"command": "get", "key": "mail.smtp.password", "user": "admin"}, audit[0].ContextMap())
+	require.Equal(t, map[string]interface{}{"command": "dump", "key": "mail.", "user": "admin"}, audit[1].ContextMap())
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintserver
+
+import (
+	"context"
+	"fmt"
+	"github.com/sprintframework/sprintframework/sprintapp"
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/sprintframework/sprintpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+)
+
+/**
+	ControlStreamService is the server streaming and unary extension of the control service.
+ */
+
+type controlStreamServer interface {
+	JobFollow(req *sprintpb.Command, stream grpc.ServerStream) error
+	WatchConfig(req *sprintpb.Command, stream grpc.ServerStream) error
+	Auth(ctx context.Context, req *sprintpb.Command) (*sprintpb.CommandResult, error)
+}
+
+/**
+	Optional extension of the job service that runs the job and streams its output.
+ */
+
+type jobFollower interface {
+	FollowJob(ctx context.Context, username, name string, cb func(line string) bool) error
+}
+
+/**
+	Optional extension of the config repository that tells when the watcher stops.
+ */
+
+type configWatcher interface {
+	WatchUntilDone(ctx context.Context, prefix string, cb func(key, value string) bool) (<-chan struct{}, error)
+}
+
+func controlStreamServiceDesc() *grpc.ServiceDesc {
+
+	jobFollow := sprintutils.JobFollowStream
+	jobFollow.Handler = func(srv interface{}, stream grpc.ServerStream) error {
+		req := new(sprintpb.Command)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		return srv.(controlStreamServer).JobFollow(req, stream)
+	}
+
+	watchConfig := sprintutils.WatchConfigStream
+	watchConfig.Handler = func(srv interface{}, stream grpc.ServerStream) error {
+		req := new(sprintpb.Command)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		return srv.(controlStreamServer).WatchConfig(req, stream)
+	}
+
+	auth := grpc.MethodDesc{
+		MethodName: sprintutils.AuthMethodName,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := new(sprintpb.Command)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return srv.(controlStreamServer).Auth(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: sprintutils.ControlUnaryMethod(sprintutils.AuthMethodName),
+			}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(controlStreamServer).Auth(ctx, req.(*sprintpb.Command))
+			})
+		},
+	}
+
+	return &grpc.ServiceDesc{
+		ServiceName: sprintutils.ControlStreamServiceName,
+		HandlerType: (*controlStreamServer)(nil),
+		Methods:     []grpc.MethodDesc{auth},
+		Streams:     []grpc.StreamDesc{jobFollow, watchConfig},
+		Metadata:    "control_stream",
+	}
+}
+
+func (t *implGrpcControlServer) JobFollow(req *sprintpb.Command, stream grpc.ServerStream) (err error) {
+
+	defer sprintutils.PanicToError(&err)
+
+	user, ok := t.AuthorizationMiddleware.GetUser(stream.Context())
+	if !ok {
+		return ErrAuthUserNotFound
+	}
+
+	if user.Roles == nil || !user.Roles["ADMIN"] {
+		return ErrAuthAdminRequired
+	}
+
+	if len(req.Args) < 1 {
+		return status.Error(codes.InvalidArgument, "job follow command needs job name argument")
+	}
+	jobName := req.Args[0]
+
+	follower, ok := t.JobService.(jobFollower)
+	if !ok {
+		return status.Error(codes.Unimplemented, "job service does not support output streaming")
+	}
+
+	err = follower.FollowJob(stream.Context(), user.Username, jobName, func(line string) bool {
+		return stream.SendMsg(&sprintpb.CommandResult{Content: line}) == nil
+	})
+	if err != nil {
+		return status.Errorf(codes.Aborted, "job '%s' failed, %v", jobName, err)
+	}
+
+	return stream.SendMsg(&sprintpb.CommandResult{Content: fmt.Sprintf("job '%s' succeeded", jobName)})
+}
+
+func (t *implGrpcControlServer) WatchConfig(req *sprintpb.Command, stream grpc.ServerStream) (err error) {
+
+	defer sprintutils.PanicToError(&err)
+
+	user, ok := t.AuthorizationMiddleware.GetUser(stream.Context())
+	if !ok {
+		return ErrAuthUserNotFound
+	}
+
+	if user.Roles == nil || !user.Roles["ADMIN"] {
+		return ErrAuthAdminRequired
+	}
+
+	var prefix string
+	if len(req.Args) > 0 {
+		prefix = req.Args[0]
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendErr error
+	mask := sprintapp.ConfigMaskPolicy(t.Properties)
+
+	// callback is called sequentially from the single watcher goroutine
+	cb := func(key, value string) bool {
+		value = mask.Mask(key, value)
+		sendErr = stream.SendMsg(&sprintpb.CommandResult{Content: fmt.Sprintf("%s: %s", key, value)})
+		if sendErr != nil {
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	t.Log.Info("ConfigWatch", zap.String("prefix", prefix), zap.String("user", user.Username))
+
+	if watcher, ok := t.ConfigRepository.(configWatcher); ok {
+		done, err := watcher.WatchUntilDone(ctx, prefix, cb)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-done:
+		case <-stream.Context().Done():
+			// watcher could be in the middle of sending, the stream must not be used after return
+			cancel()
+			<-done
+			return nil
+		}
+		if sendErr != nil {
+			return sendErr
+		}
+		if stream.Context().Err() == nil {
+			return status.Errorf(codes.ResourceExhausted, "config watcher with prefix '%s' disconnected", prefix)
+		}
+		return nil
+	}
+
+	// without done channel guard the stream from the callback running after return
+	var mu sync.Mutex
+	finished := false
+	guarded := func(key, value string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return !finished && cb(key, value)
+	}
+
+	if _, err := t.ConfigRepository.Watch(ctx, prefix, guarded); err != nil {
+		return err
+	}
+	<-ctx.Done()
+
+	mu.Lock()
+	finished = true
+	mu.Unlock()
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintserver
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintpb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+	"time"
+)
+
+type adminMiddleware struct {
+	sprint.AuthorizationMiddleware
+}
+
+func (t adminMiddleware) GetUser(ctx context.Context) (*sprint.AuthorizedUser, bool) {
+	return &sprint.AuthorizedUser{Username: "admin", Roles: map[string]bool{"ADMIN": true}}, true
+}
+
+type recordingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	mu       sync.Mutex
+	messages []string
+	closed   bool
+}
+
+func (t *recordingStream) Context() context.Context {
+	return t.ctx
+}
+
+func (t *recordingStream) SendMsg(m interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		panic("send on finished stream")
+	}
+	t.messages = append(t.messages, m.(*sprintpb.CommandResult).Content)
+	return nil
+}
+
+func (t *recordingStream) finish() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return t.messages
+}
+
+/**
+	Delivers the changes to the watcher and then stops it, or keeps sending until the watcher is cancelled.
+ */
+
+type scriptedWatcher struct {
+	sprint.ConfigRepository
+	changes     []string
+	disconnect  bool
+}
+
+func (t *scriptedWatcher) WatchUntilDone(ctx context.Context, prefix string, cb func(key, value string) bool) (<-chan struct{}, error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, key := range t.changes {
+			if !cb(key, "value") {
+				return
+			}
+		}
+		if t.disconnect {
+			return
+		}
+		for ctx.Err() == nil {
+			cb("late", "value")
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return done, nil
+}
+
+func newWatchServer(watcher *scriptedWatcher) *implGrpcControlServer {
+	return &implGrpcControlServer{
+		AuthorizationMiddleware: adminMiddleware{},
+		ConfigRepository:        watcher,
+		Log:                     zap.NewNop(),
+	}
+}
+
+func TestWatchConfigDisconnect(t *testing.T) {
+
+	server := newWatchServer(&scriptedWatcher{changes: []string{"a", "b"}, disconnect: true})
+	stream := &recordingStream{ctx: context.Background()}
+
+	err := server.WatchConfig(&sprintpb.Command{Args: []string{"a"}}, stream)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"a: value", "b: value"}, stream.finish())
+}
+
+func TestWatchConfigCancel(t *testing.T) {
+
+	server := newWatchServer(&scriptedWatcher{changes: []string{"a"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &recordingStream{ctx: ctx}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	require.NoError(t, server.WatchConfig(&sprintpb.Command{}, stream))
+
+	// the watcher stopped before return, so nothing is sent to the finished stream
+	messages := stream.finish()
+	require.Equal(t, "a: value", messages[0])
+	time.Sleep(20 * time.Millisecond)
+}
+
+/**
+	Job service that streams the scripted output of the job and then returns the result.
+ */
+
+type scriptedFollower struct {
+	sprint.JobService
+	output  []string
+	result  error
+}
+
+func (t *scriptedFollower) FollowJob(ctx context.Context, username, name string, cb func(line string) bool) error {
+	for _, line := range t.output {
+		if !cb(line) {
+			return errors.New("follower disconnected")
+		}
+	}
+	return t.result
+}
+
+func newFollowServer(follower *scriptedFollower) *implGrpcControlServer {
+	return &implGrpcControlServer{
+		AuthorizationMiddleware: adminMiddleware{},
+		JobService:              follower,
+		Log:                     zap.NewNop(),
+	}
+}
+
+func TestJobFollow(t *testing.T) {
+
+	server := newFollowServer(&scriptedFollower{output: []string{"one", "two"}})
+	stream := &recordingStream{ctx: context.Background()}
+
+	require.NoError(t, server.JobFollow(&sprintpb.Command{Args: []string{"report"}}, stream))
+	require.Equal(t, []string{"one", "two", "job 'report' succeeded"}, stream.finish())
+
+	stream = &recordingStream{ctx: context.Background()}
+	err := server.JobFollow(&sprintpb.Command{}, stream)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestJobFollowFailed(t *testing.T) {
+
+	server := newFollowServer(&scriptedFollower{output: []string{"one"}, result: errors.New("broken")})
+	stream := &recordingStream{ctx: context.Background()}
+
+	err := server.JobFollow(&sprintpb.Command{Args: []string{"report"}}, stream)
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.Equal(t, []string{"one"}, stream.finish())
+}
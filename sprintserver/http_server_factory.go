@@ -6,6 +6,7 @@
 package sprintserver
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/codeallergy/glue"
@@ -14,9 +15,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sprintframework/sprint"
 	"github.com/sprintframework/sprintframework/sprintutils"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/protobuf/encoding/protojson"
+	"net"
 	"net/http"
 	"reflect"
 	"strings"
@@ -32,9 +35,13 @@ type implHttpServerFactory struct {
 	AutocertManager  *autocert.Manager                 `inject:"optional"`
 	TlsConfig        *tls.Config                       `inject:"optional"`
 
-	beanName     string
+	beanName      string
+	readTimeout   atomic.Duration
+	writeTimeout  atomic.Duration
 }
 
+type httpConnKey struct{}
+
 func HttpServerFactory(beanName string) glue.FactoryBean {
 	return &implHttpServerFactory{beanName: beanName}
 }
@@ -110,9 +117,12 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 		}
 	}
 
-	readTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "read-timeout"), 30 * time.Second)
-	writeTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "write-timeout"), 30 * time.Second)
-	idleTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "idle-timeout"), time.Minute)
+	readTimeout := t.getTimeout("read-timeout", 30 * time.Second)
+	t.readTimeout.Store(readTimeout)
+	t.writeTimeout.Store(t.getTimeout("write-timeout", 30 * time.Second))
+
+	// http.Server reads own timeouts without synchronization, so header and idle timeouts need restart
+	idleTimeout := t.getTimeout("idle-timeout", time.Minute)
 
 	t.Log.Info("HTTPServerFactory",
 		zap.String("listenAddr", listenAddr),
@@ -125,10 +135,12 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 
 	srv := &http.Server{
 		Addr: listenAddr,
-		Handler: mux,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
+		Handler: t.withDeadlines(mux),
+		ReadHeaderTimeout: readTimeout,
 		IdleTimeout: idleTimeout,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, httpConnKey{}, conn)
+		},
 	}
 
 	if t.TlsConfig != nil {
@@ -139,6 +151,51 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 
 }
 
+func (t *implHttpServerFactory) getTimeout(name string, def time.Duration) time.Duration {
+	return t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, name), def)
+}
+
+/**
+	Sets read and write deadlines of the connection on each request from the current timeouts.
+	HTTP/2 streams share the connection, therefore they are served without deadlines.
+ */
+
+func (t *implHttpServerFactory) withDeadlines(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, ok := r.Context().Value(httpConnKey{}).(net.Conn); ok && r.ProtoMajor == 1 {
+			now := time.Now()
+			conn.SetReadDeadline(deadline(now, t.readTimeout.Load()))
+			conn.SetWriteDeadline(deadline(now, t.writeTimeout.Load()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func deadline(now time.Time, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return now.Add(timeout)
+}
+
+func (t *implHttpServerFactory) ReloadPrefixes() []string {
+	return []string{ t.beanName + ".read-timeout", t.beanName + ".write-timeout" }
+}
+
+/**
+	Timeouts are applied to the next requests of the open and new connections.
+ */
+
+func (t *implHttpServerFactory) Reload(key, value string) error {
+	switch key[len(t.beanName)+1:] {
+	case "read-timeout":
+		t.readTimeout.Store(t.getTimeout("read-timeout", 30 * time.Second))
+	case "write-timeout":
+		t.writeTimeout.Store(t.getTimeout("write-timeout", 30 * time.Second))
+	}
+	return nil
+}
+
 func (t *implHttpServerFactory) ObjectType() reflect.Type {
 	return sprint.HttpServerClass
 }
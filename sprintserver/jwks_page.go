@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintserver
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
+	"net/http"
+)
+
+/**
+	Optional extension of the authorization middleware that publishes public keys of the JWT keyring.
+ */
+
+type jwksProvider interface {
+	JWKS() ([]byte, error)
+}
+
+type implJwksPage struct {
+	AuthorizationMiddleware sprint.AuthorizationMiddleware `inject`
+
+	pattern  string
+	provider jwksProvider
+}
+
+/**
+	Serves JWK Set of the authorization middleware, usually on '/.well-known/jwks.json' of the gateway server.
+ */
+
+func JwksPage(pattern string) sprint.Router {
+	return &implJwksPage{
+		pattern: pattern,
+	}
+}
+
+func (t *implJwksPage) PostConstruct() error {
+	provider, ok := t.AuthorizationMiddleware.(jwksProvider)
+	if !ok {
+		return errors.New("authorization middleware does not support JWKS")
+	}
+	t.provider = provider
+	return nil
+}
+
+func (t *implJwksPage) Pattern() string {
+	return t.pattern
+}
+
+func (t *implJwksPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			http.Error(w, fmt.Sprintf("%v", r), http.StatusInternalServerError)
+		}
+	}()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, err := t.provider.JWKS()
+	if err != nil {
+		http.Error(w, "keyring is not available", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(content)
+}
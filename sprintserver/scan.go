@@ -8,6 +8,7 @@ package sprintserver
 import (
 	"github.com/codeallergy/glue"
 	"github.com/sprintframework/sprint"
+	"github.com/sprintframework/sprintframework/sprintapp"
 	"google.golang.org/grpc"
 	"net/http"
 )
@@ -28,6 +29,7 @@ func (t *grpcServerScanner) Beans() []interface{} {
 	beans := []interface{}{
 		AuthorizationMiddleware(),
 		GrpcServerFactory(t.beanName),
+		sprintapp.PropertyReloader(),
 		&struct {
 			// make them visible
 			Servers     []sprint.Server `inject:"optional"`
@@ -53,6 +55,7 @@ func HttpServerScanner(beanName string, scan... interface{}) glue.Scanner {
 func (t *httpServerScanner) Beans() []interface{} {
 	beans := []interface{}{
 		HttpServerFactory(t.beanName),
+		sprintapp.PropertyReloader(),
 		&struct {
 			// make them visible
 			Servers     []sprint.Server `inject:"optional"`
@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	DefaultRSAKeySize = 2048
+
+	AuthKeyAlgorithms = []string{"HS256", "RS256", "ES256", "EdDSA"}
+)
+
+/**
+	JWT key with the signing method, SignKey is nil for keys that only verify tokens, like public keys of other issuers.
+ */
+
+type AuthKey struct {
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+func HmacAuthKey(secretKey []byte) *AuthKey {
+	return &AuthKey{
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   secretKey,
+		VerifyKey: secretKey,
+	}
+}
+
+/**
+	Parses the key from the config value, PEM encoded RSA, ECDSA or Ed25519 keys are asymmetric,
+	any other value is the base64 encoded HMAC secret.
+ */
+
+func ParseAuthKey(value string) (*AuthKey, error) {
+
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "-----BEGIN") {
+		secretKey, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid base64 secret key, %v", err)
+		}
+		return HmacAuthKey(secretKey), nil
+	}
+
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Errorf("parse RSA private key, %v", err)
+		}
+		return privateAuthKey(key)
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Errorf("parse EC private key, %v", err)
+		}
+		return privateAuthKey(key)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Errorf("parse PKCS8 private key, %v", err)
+		}
+		return privateAuthKey(key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Errorf("parse RSA public key, %v", err)
+		}
+		return publicAuthKey(key)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Errorf("parse public key, %v", err)
+		}
+		return publicAuthKey(key)
+	default:
+		return nil, errors.Errorf("unsupported PEM block '%s'", block.Type)
+	}
+}
+
+func privateAuthKey(key interface{}) (*AuthKey, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &AuthKey{Method: jwt.SigningMethodRS256, SignKey: k, VerifyKey: &k.PublicKey}, nil
+	case *ecdsa.PrivateKey:
+		method, err := ecdsaSigningMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthKey{Method: method, SignKey: k, VerifyKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &AuthKey{Method: jwt.SigningMethodEdDSA, SignKey: k, VerifyKey: k.Public()}, nil
+	default:
+		return nil, errors.Errorf("unsupported private key type %T", key)
+	}
+}
+
+func publicAuthKey(key interface{}) (*AuthKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &AuthKey{Method: jwt.SigningMethodRS256, VerifyKey: k}, nil
+	case *ecdsa.PublicKey:
+		method, err := ecdsaSigningMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthKey{Method: method, VerifyKey: k}, nil
+	case ed25519.PublicKey:
+		return &AuthKey{Method: jwt.SigningMethodEdDSA, VerifyKey: k}, nil
+	default:
+		return nil, errors.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func ecdsaSigningMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	default:
+		return nil, errors.Errorf("unsupported elliptic curve '%s'", curve.Params().Name)
+	}
+}
+
+/**
+	Generates the config value of the new key, base64 secret for HS256 and PKCS8 PEM for others.
+ */
+
+func GenerateAuthKey(alg string) (string, error) {
+
+	var key interface{}
+	var err error
+
+	switch alg {
+	case "", "HS256":
+		return GenerateToken()
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, DefaultRSAKeySize)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", errors.Errorf("unsupported JWT algorithm '%s', expected one of %v", alg, AuthKeyAlgorithms)
+	}
+
+	if err != nil {
+		return "", errors.Errorf("generate %s key, %v", alg, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+/**
+	Public keys of the keyring in JWK Set format (RFC 7517), HMAC secrets are never exposed.
+ */
+
+func (t *AuthKeyring) JWKS() ([]byte, error) {
+
+	keys := make([]*jsonWebKey, 0, len(t.Keys))
+	for kid, key := range t.Keys {
+
+		jwk := &jsonWebKey{
+			Kid: kid,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch k := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = Encoding.EncodeToString(k.N.Bytes())
+			jwk.E = Encoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (k.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = k.Curve.Params().Name
+			jwk.X = Encoding.EncodeToString(k.X.FillBytes(make([]byte, size)))
+			jwk.Y = Encoding.EncodeToString(k.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = Encoding.EncodeToString(k)
+		default:
+			continue
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return json.Marshal(map[string]interface{}{"keys": keys})
+}
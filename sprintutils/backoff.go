@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils
+
+import (
+	"math/rand"
+	"time"
+)
+
+/**
+	Exponential backoff with optional jitter.
+	Jitter is a fraction of the delay in range [0, 1] randomly added or subtracted to the delay.
+ */
+
+type Backoff struct {
+	Initial  time.Duration
+	Max      time.Duration
+	Jitter   float64
+}
+
+/**
+	Returns delay before the next attempt, where attempt is the number of already failed attempts starting from 1.
+ */
+
+func (t Backoff) Delay(attempt int) time.Duration {
+
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := t.Initial
+	for i := 1; i < attempt && (t.Max <= 0 || delay < t.Max); i++ {
+		delay *= 2
+	}
+
+	if t.Max > 0 && delay > t.Max {
+		delay = t.Max
+	}
+
+	if t.Jitter > 0 {
+		jitter := t.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delta := float64(delay) * jitter
+		delay = time.Duration(float64(delay) - delta + rand.Float64()*2*delta)
+		if t.Max > 0 && delay > t.Max {
+			delay = t.Max
+		}
+	}
+
+	return delay
+}
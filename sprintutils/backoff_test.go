@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+
+	b := sprintutils.Backoff{
+		Initial: time.Second,
+		Max:     10 * time.Second,
+	}
+
+	require.Equal(t, time.Second, b.Delay(1))
+	require.Equal(t, 2*time.Second, b.Delay(2))
+	require.Equal(t, 4*time.Second, b.Delay(3))
+	require.Equal(t, 10*time.Second, b.Delay(5))
+	require.Equal(t, 10*time.Second, b.Delay(100))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := b.Delay(2)
+		require.True(t, delay >= time.Second && delay <= 3*time.Second, delay.String())
+	}
+
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils
+
+import (
+	"fmt"
+	"google.golang.org/grpc"
+)
+
+/**
+	Server streaming and unary extension of the control service.
+	Uses messages from sprintpb, therefore does not need separate generated code.
+ */
+
+var ControlStreamServiceName = "sprint.ControlStreamService"
+
+var JobFollowStream = grpc.StreamDesc{
+	StreamName:    "JobFollow",
+	ServerStreams: true,
+}
+
+var WatchConfigStream = grpc.StreamDesc{
+	StreamName:    "WatchConfig",
+	ServerStreams: true,
+}
+
+/**
+	Unary method with sprintpb.Command request and sprintpb.CommandResult response for auth commands.
+ */
+
+var AuthMethodName = "Auth"
+
+func ControlStreamMethod(desc *grpc.StreamDesc) string {
+	return fmt.Sprintf("/%s/%s", ControlStreamServiceName, desc.StreamName)
+}
+
+func ControlUnaryMethod(methodName string) string {
+	return fmt.Sprintf("/%s/%s", ControlStreamServiceName, methodName)
+}
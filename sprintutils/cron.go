@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+	Schedule calculates the next fire time of the job after the given time.
+	Returns zero time if the schedule would never fire again.
+ */
+
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (t everySchedule) Next(from time.Time) time.Time {
+	return from.Add(t.interval).Truncate(time.Second)
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{0, 59, nil}
+	hourField   = cronField{0, 23, nil}
+	domField    = cronField{1, 31, nil}
+	monthField  = cronField{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+/**
+	Parses the job schedule. Supports standard five fields cron expressions 'minute hour day-of-month month day-of-week',
+	descriptors like '@daily' or '@hourly' and fixed intervals in the form of '@every 1h30m'.
+ */
+
+func ParseSchedule(spec string) (Schedule, error) {
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, errors.Errorf("invalid interval in schedule '%s', %v", spec, err)
+		}
+		if interval < time.Second {
+			return nil, errors.Errorf("interval in schedule '%s' must be at least one second", spec)
+		}
+		return everySchedule{interval}, nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, errors.Errorf("unknown schedule descriptor '%s'", spec)
+		}
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("expected 5 fields in cron expression '%s', but found %d", spec, len(fields))
+	}
+
+	var (
+		s   cronSchedule
+		err error
+	)
+
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, errors.Errorf("minute field of '%s', %v", spec, err)
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, errors.Errorf("hour field of '%s', %v", spec, err)
+	}
+	if s.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, errors.Errorf("day of month field of '%s', %v", spec, err)
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, errors.Errorf("month field of '%s', %v", spec, err)
+	}
+	if s.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, errors.Errorf("day of week field of '%s', %v", spec, err)
+	}
+	// 7 is the alias for sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return &s, nil
+}
+
+func (f cronField) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+
+		step := 1
+		if i := strings.IndexByte(part, '/'); i != -1 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.Errorf("invalid step in '%s'", part)
+			}
+			part = part[:i]
+		}
+
+		from, to := f.min, f.max
+		if part != "*" && part != "?" {
+			if i := strings.IndexByte(part, '-'); i != -1 {
+				var err error
+				if from, err = f.value(part[:i]); err != nil {
+					return 0, err
+				}
+				if to, err = f.value(part[i+1:]); err != nil {
+					return 0, err
+				}
+			} else {
+				var err error
+				if from, err = f.value(part); err != nil {
+					return 0, err
+				}
+				if step == 1 {
+					to = from
+				}
+			}
+		}
+
+		if from > to {
+			return 0, errors.Errorf("invalid range in '%s'", part)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if f.names != nil {
+		if v, ok := f.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value '%s'", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, errors.Errorf("value '%d' out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (t *cronSchedule) Next(from time.Time) time.Time {
+
+	next := from.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+
+		if t.month&(1<<uint(next.Month())) == 0 {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !t.matchDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if t.hour&(1<<uint(next.Hour())) == 0 {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if t.minute&(1<<uint(next.Minute())) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
+
+func (t *cronSchedule) matchDay(at time.Time) bool {
+	domMatch := t.dom&(1<<uint(at.Day())) != 0
+	dowMatch := t.dow&(1<<uint(at.Weekday())) != 0
+	// standard cron behavior: if both fields are restricted, then any of them could match
+	if !t.domStar && !t.dowStar {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
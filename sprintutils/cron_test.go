@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+
+	from := time.Date(2023, time.March, 15, 10, 30, 20, 0, time.UTC)
+
+	s, err := sprintutils.ParseSchedule("0 3 * * *")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 16, 3, 0, 0, 0, time.UTC), s.Next(from))
+
+	s, err = sprintutils.ParseSchedule("*/15 * * * *")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC), s.Next(from))
+
+	s, err = sprintutils.ParseSchedule("0 0 1 jan *")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), s.Next(from))
+
+	// 2023-03-15 is wednesday
+	s, err = sprintutils.ParseSchedule("30 9 * * mon-fri")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 16, 9, 30, 0, 0, time.UTC), s.Next(from))
+
+	s, err = sprintutils.ParseSchedule("@daily")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC), s.Next(from))
+
+}
+
+func TestEverySchedule(t *testing.T) {
+
+	from := time.Date(2023, time.March, 15, 10, 30, 20, 0, time.UTC)
+
+	s, err := sprintutils.ParseSchedule("@every 1h30m")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.March, 15, 12, 0, 20, 0, time.UTC), s.Next(from))
+
+}
+
+func TestInvalidSchedule(t *testing.T) {
+
+	for _, spec := range []string{"", "* * *", "61 * * * *", "@sometimes", "@every 1ms", "5-1 * * * *"} {
+		_, err := sprintutils.ParseSchedule(spec)
+		require.Error(t, err, spec)
+	}
+
+}
@@ -6,6 +6,7 @@
 package sprintutils
 
 import (
+	"go.uber.org/atomic"
 	"sync"
 	"time"
 )
@@ -15,22 +16,40 @@ const (
 )
 
 type RateLimiter struct {
-	Limit  time.Duration
+	Limit  time.Duration  // initial limit, use SetLimit to change it at runtime
 	mu  sync.Mutex
 	lastReqTime time.Time
+	current  atomic.Duration
+}
+
+/**
+	Changes the limit at runtime without waiting for the running request, applies to the next request.
+	Zero limit restores the initial one.
+ */
+
+func (t *RateLimiter) SetLimit(limit time.Duration) {
+	t.current.Store(limit)
+}
+
+func (t *RateLimiter) GetLimit() time.Duration {
+	if limit := t.current.Load(); limit != 0 {
+		return limit
+	}
+	if t.Limit != 0 {
+		return t.Limit
+	}
+	return defaultRateLimit
 }
 
 func (t *RateLimiter) Do(fn func() error) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if t.Limit == 0 {
-		t.Limit = defaultRateLimit
-	}
+	limit := t.GetLimit()
 
 	lastreq := time.Since(t.lastReqTime)
-	if lastreq < t.Limit {
-		time.Sleep(t.Limit - lastreq)
+	if lastreq < limit {
+		time.Sleep(limit - lastreq)
 	}
 	err := fn()
 	t.lastReqTime = time.Now()
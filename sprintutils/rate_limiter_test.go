@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSetLimit(t *testing.T) {
+
+	limiter := &sprintutils.RateLimiter{Limit: time.Second}
+	require.Equal(t, time.Second, limiter.GetLimit())
+
+	running := make(chan struct{})
+	release := make(chan struct{})
+	go limiter.Do(func() error {
+		close(running)
+		<-release
+		return nil
+	})
+	<-running
+
+	// does not wait for the running request
+	limiter.SetLimit(time.Millisecond)
+	require.Equal(t, time.Millisecond, limiter.GetLimit())
+	close(release)
+
+	start := time.Now()
+	require.NoError(t, limiter.Do(func() error {
+		return nil
+	}))
+	require.True(t, time.Since(start) < time.Second)
+
+	limiter.SetLimit(0)
+	require.Equal(t, time.Second, limiter.GetLimit())
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+/**
+	Encrypted values have the prefix with the version of the format, followed by base64 encoded nonce and ciphertext.
+ */
+
+var EncryptedValuePrefix = "enc:v1:"
+
+var ErrInvalidEncryptedValue = errors.New("invalid encrypted value")
+
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, EncryptedValuePrefix)
+}
+
+/**
+	Derives 256-bit key from the token for the specific purpose, different purposes give independent keys.
+ */
+
+func DeriveKey(token, purpose string) ([]byte, error) {
+	key := make([]byte, DefaultTokenSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(token), nil, []byte(purpose)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/**
+	Generates random 256-bit data key.
+ */
+
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, DefaultTokenSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/**
+	Encrypts the value with AES-GCM, associated data binds the ciphertext to the context, for example the config key.
+ */
+
+func EncryptValue(key []byte, plaintext, associatedData string) (string, error) {
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return EncryptedValuePrefix + Encoding.EncodeToString(sealed), nil
+}
+
+func DecryptValue(key []byte, value, associatedData string) (string, error) {
+
+	if !IsEncryptedValue(value) {
+		return "", ErrInvalidEncryptedValue
+	}
+
+	sealed, err := Encoding.DecodeString(value[len(EncryptedValuePrefix):])
+	if err != nil {
+		return "", ErrInvalidEncryptedValue
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidEncryptedValue
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(associatedData))
+	if err != nil {
+		return "", errors.Errorf("decrypt value, %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
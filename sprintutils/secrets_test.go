@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package sprintutils_test
+
+import (
+	"github.com/sprintframework/sprintframework/sprintutils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEncryptValue(t *testing.T) {
+
+	key, err := sprintutils.GenerateKey()
+	require.NoError(t, err)
+
+	value, err := sprintutils.EncryptValue(key, "secret", "mailgun.password")
+	require.NoError(t, err)
+	require.True(t, sprintutils.IsEncryptedValue(value))
+
+	plaintext, err := sprintutils.DecryptValue(key, value, "mailgun.password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+
+	_, err = sprintutils.DecryptValue(key, value, "other.password")
+	require.Error(t, err)
+
+	otherKey, err := sprintutils.DeriveKey("token", "config")
+	require.NoError(t, err)
+	_, err = sprintutils.DecryptValue(otherKey, value, "mailgun.password")
+	require.Error(t, err)
+
+	_, err = sprintutils.DecryptValue(key, "secret", "mailgun.password")
+	require.Equal(t, sprintutils.ErrInvalidEncryptedValue, err)
+}
+
+func TestDeriveKey(t *testing.T) {
+
+	a, err := sprintutils.DeriveKey("token", "config")
+	require.NoError(t, err)
+
+	b, err := sprintutils.DeriveKey("token", "config")
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+
+	c, err := sprintutils.DeriveKey("token", "other")
+	require.NoError(t, err)
+	require.NotEqual(t, a, c)
+}
@@ -7,6 +7,7 @@ package sprintutils
 
 import (
 	"crypto/rand"
+	"fmt"
 	"encoding/base64"
 	"encoding/hex"
 	"github.com/golang-jwt/jwt"
@@ -14,8 +15,10 @@ import (
 	"github.com/codeallergy/base62"
 	"github.com/sprintframework/sprint"
 	"io"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -26,6 +29,8 @@ var (
 	NodeIdSize = NodeIdBits / 8
 
 	Encoding = base64.RawURLEncoding
+
+	LegacyNoDeadline int64 = math.MaxInt64 // LegacyUntil of the policy that accepts legacy tokens without deadline
 )
 
 func GenerateLongId() (string, error) {
@@ -96,61 +101,201 @@ func ParseNodeId(nodeId string) (uint64, error) {
 	return strconv.ParseUint(nodeId, 16, NodeIdBits)
 }
 
+/**
+	Kinds of JWT token verification failures, messages of kinds are safe to return to clients.
+ */
+
+var (
+	ErrTokenMalformed   = errors.New("malformed token")
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	ErrTokenSignature   = errors.New("invalid token signature")
+	ErrTokenClaims      = errors.New("invalid token claims")
+	ErrTokenRevoked     = errors.New("token is revoked")
+)
+
+/**
+	Verification failure of JWT token, the reason is for logs and never contains the token.
+	Use errors.Cause to get the kind of the failure.
+ */
+
+type AuthTokenError struct {
+	Kind   error
+	Reason string
+}
+
+func (e *AuthTokenError) Error() string {
+	if e.Reason == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ", " + e.Reason
+}
+
+func (e *AuthTokenError) Cause() error {
+	return e.Kind
+}
+
+func (e *AuthTokenError) Unwrap() error {
+	return e.Kind
+}
+
+func authTokenError(kind error, format string, args ...interface{}) error {
+	return &AuthTokenError{Kind: kind, Reason: fmt.Sprintf(format, args...)}
+}
+
+/**
+	Gets the message of the failure safe to return to clients, all other errors are hidden behind the generic one.
+ */
+
+func AuthTokenErrorMessage(err error) string {
+	if e, ok := err.(*AuthTokenError); ok {
+		return e.Kind.Error()
+	}
+	return "invalid token"
+}
+
 type UserClaims struct {
 	Roles     []string            `json:"roles"`
 	Context   map[string]string   `json:"ctx"`
 	jwt.StandardClaims
 }
 
-func GenerateAuthToken(secretKey []byte, user *sprint.AuthorizedUser) (string, error) {
+/**
+	Registered claims of the application, empty Issuer or Audience are neither issued nor enforced.
+	Leeway is the allowed clock skew in seconds between nodes issuing and verifying tokens.
+ */
+
+type AuthTokenPolicy struct {
+	Issuer   string
+	Audience string
+	Leeway   int64
+	LegacyUntil int64  // unix seconds, legacy tokens are accepted until then, zero rejects them
+}
 
-	if secretKey == nil {
-		return "", errors.New("empty secretKey")
+/**
+	Keyring of JWT signing keys by key ID, the active key signs new tokens, all keys verify them.
+	Empty key ID is the legacy 'jwt.secret.key' of tokens without 'kid' header.
+ */
+
+type AuthKeyring struct {
+	Active string
+	Keys   map[string]*AuthKey
+}
+
+func SingleKeyring(kid string, key *AuthKey) *AuthKeyring {
+	return &AuthKeyring{
+		Active: kid,
+		Keys:   map[string]*AuthKey{kid: key},
+	}
+}
+
+func (t *AuthKeyring) SigningKey() (string, *AuthKey, error) {
+	if t == nil {
+		return "", nil, errors.New("empty keyring")
+	}
+	key, ok := t.Keys[t.Active]
+	if !ok || key.SignKey == nil {
+		return "", nil, errors.Errorf("signing key '%s' not found in keyring", t.Active)
+	}
+	return t.Active, key, nil
+}
+
+func (t *AuthKeyring) VerificationKey(kid string) (*AuthKey, error) {
+	if t == nil {
+		return nil, errors.New("empty keyring")
+	}
+	key, ok := t.Keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown jwt key id '%s'", kid)
+	}
+	return key, nil
+}
+
+/**
+	Generates JWT token with the unique JWT ID, random one is generated if tokenId is empty.
+ */
+
+func GenerateAuthToken(keyring *AuthKeyring, tokenId string, user *sprint.AuthorizedUser, policy *AuthTokenPolicy) (string, error) {
+
+	kid, key, err := keyring.SigningKey()
+	if err != nil {
+		return "", err
 	}
 
 	if user == nil {
 		return "", errors.New("empty user")
 	}
 
+	if tokenId == "" {
+		if tokenId, err = GenerateLongId(); err != nil {
+			return "", err
+		}
+	}
+
 	var roles []string
 	for key, _ := range user.Roles {
 		roles = append(roles, key)
 	}
 
+	now := time.Now().Unix()
 	claims := &UserClaims{
 		StandardClaims: jwt.StandardClaims{
-			Id:        user.Username,
+			Id:        tokenId,
+			Subject:   user.Username,
 			ExpiresAt: user.ExpiresAt,
+			IssuedAt:  now,
+			NotBefore: now,
 		},
 		Roles:   roles,
 		Context: user.Context,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	if policy != nil {
+		claims.Issuer = policy.Issuer
+		claims.Audience = policy.Audience
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key.SignKey)
 
 }
 
-func VerifyAuthToken(secretKey []byte, jwtToken string) (*sprint.AuthorizedUser, error) {
-
-	if secretKey == nil {
-		return nil, errors.New("empty secretKey")
-	}
+func VerifyAuthToken(keyring *AuthKeyring, jwtToken string, policy *AuthTokenPolicy) (*sprint.AuthorizedUser, error) {
 
 	var claims UserClaims
-
-	token, err := jwt.ParseWithClaims(jwtToken, &claims, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
+	var kid string
+
+	// registered claims are verified below with leeway
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(jwtToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ = token.Header["kid"].(string)
+		key, err := keyring.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		// the key defines the algorithm, never the token
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.Errorf("unexpected signing method '%s'", token.Method.Alg())
+		}
+		return key.VerifyKey, nil
 	})
 
-	decodedToken, _ := Encoding.DecodeString(jwtToken)
-
 	if err != nil {
-		return nil, errors.Errorf("wrong jwt token '%s'", decodedToken)
+		return nil, parseTokenError(err)
 	}
 
-	if !token.Valid {
-		return nil, errors.Errorf("expired jwt token '%s'", decodedToken)
+	now := time.Now().Unix()
+	if kid == "" && claims.Subject == "" && claims.IssuedAt == 0 {
+		if err := verifyLegacyClaims(&claims.StandardClaims, policy, now); err != nil {
+			return nil, err
+		}
+		// legacy tokens keep the username in JWT ID
+		claims.Subject = claims.Id
+	} else if err := verifyStandardClaims(&claims.StandardClaims, policy, now); err != nil {
+		return nil, err
 	}
 
 	indexedRoles := make(map[string]bool)
@@ -159,10 +304,126 @@ func VerifyAuthToken(secretKey []byte, jwtToken string) (*sprint.AuthorizedUser,
 	}
 
 	return &sprint.AuthorizedUser{
-		Username:  claims.Id,
+		Username:  claims.Subject,
 		Roles:     indexedRoles,
 		Context:   claims.Context,
 		ExpiresAt: claims.ExpiresAt,
 		Token:     jwtToken,
 	}, nil
 }
+
+/**
+	Converts the error of the parser to the kind, the parser never includes the token in errors.
+ */
+
+func parseTokenError(err error) error {
+	ve, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return authTokenError(ErrTokenMalformed, "%v", err)
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return authTokenError(ErrTokenMalformed, "%v", ve.Inner)
+	case ve.Errors&jwt.ValidationErrorUnverifiable != 0:
+		// unknown key ID or signing method
+		return authTokenError(ErrTokenSignature, "%v", ve.Inner)
+	case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return &AuthTokenError{Kind: ErrTokenSignature}
+	default:
+		return authTokenError(ErrTokenMalformed, "%v", ve)
+	}
+}
+
+/**
+	Tokens without 'jti', 'sub' or 'iat' claims were issued before unique JWT IDs and are not accepted, except legacy ones by the policy.
+ */
+
+func verifyStandardClaims(claims *jwt.StandardClaims, policy *AuthTokenPolicy, now int64) error {
+
+	if policy == nil {
+		policy = &AuthTokenPolicy{}
+	}
+
+	if claims.Id == "" || claims.Subject == "" {
+		return authTokenError(ErrTokenClaims, "missing 'jti' or 'sub' claim")
+	}
+
+	if claims.IssuedAt == 0 {
+		return authTokenError(ErrTokenClaims, "missing 'iat' claim")
+	}
+
+	if claims.IssuedAt > now + policy.Leeway {
+		return authTokenError(ErrTokenNotValidYet, "issued at %s", time.Unix(claims.IssuedAt, 0).Format(time.RFC3339))
+	}
+
+	if err := verifyTimeClaims(claims, policy, now); err != nil {
+		return err
+	}
+
+	if policy.Issuer != "" && claims.Issuer != policy.Issuer {
+		return authTokenError(ErrTokenClaims, "wrong issuer '%s'", claims.Issuer)
+	}
+
+	if policy.Audience != "" && claims.Audience != policy.Audience {
+		return authTokenError(ErrTokenClaims, "wrong audience '%s'", claims.Audience)
+	}
+
+	return nil
+}
+
+/**
+	Legacy tokens have the username in 'jti' and neither 'sub', 'iat' nor 'kid', they are signed by 'jwt.secret.key'.
+	They are accepted only until the deadline of the policy, issuer and audience are not enforced for them.
+ */
+
+func verifyLegacyClaims(claims *jwt.StandardClaims, policy *AuthTokenPolicy, now int64) error {
+
+	if policy == nil || policy.LegacyUntil == 0 {
+		return authTokenError(ErrTokenClaims, "missing 'jti' or 'sub' claim")
+	}
+
+	if now > policy.LegacyUntil {
+		return authTokenError(ErrTokenClaims, "legacy tokens are not accepted since %s", time.Unix(policy.LegacyUntil, 0).Format(time.RFC3339))
+	}
+
+	if claims.Id == "" {
+		return authTokenError(ErrTokenClaims, "missing 'jti' claim")
+	}
+
+	return verifyTimeClaims(claims, policy, now)
+}
+
+func verifyTimeClaims(claims *jwt.StandardClaims, policy *AuthTokenPolicy, now int64) error {
+
+	if claims.NotBefore > now + policy.Leeway {
+		return authTokenError(ErrTokenNotValidYet, "not before %s", time.Unix(claims.NotBefore, 0).Format(time.RFC3339))
+	}
+
+	if claims.ExpiresAt != 0 && claims.ExpiresAt < now - policy.Leeway {
+		return authTokenError(ErrTokenExpired, "expired at %s", time.Unix(claims.ExpiresAt, 0).Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+/**
+	Checks if the string has the form of JWT token, that is three dot separated parts.
+ */
+
+func IsJwtToken(s string) bool {
+	return strings.Count(s, ".") == 2
+}
+
+/**
+	Gets JWT ID and expiration of the token without verification of the signature.
+ */
+
+func ParseAuthTokenId(jwtToken string) (id string, expiresAt int64, err error) {
+
+	var claims UserClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(jwtToken, &claims); err != nil {
+		return "", 0, parseTokenError(err)
+	}
+
+	return claims.Id, claims.ExpiresAt, nil
+}
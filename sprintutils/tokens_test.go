@@ -6,11 +6,16 @@
 package sprintutils_test
 
 import (
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+	"github.com/sprintframework/sprint"
 	"github.com/sprintframework/sprintframework/sprintutils"
 	"github.com/stretchr/testify/require"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLongId(t *testing.T) {
@@ -43,3 +48,195 @@ func TestShowId(t *testing.T) {
 
 	println(sprintutils.EncodeId(num+1))
 }
+
+func TestAuthTokenClaims(t *testing.T) {
+
+	keyring := sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("secret")))
+	policy := &sprintutils.AuthTokenPolicy{Issuer: "sprint", Audience: "sprint", Leeway: 60}
+
+	user := &sprint.AuthorizedUser{
+		Username:  "admin",
+		Roles:     map[string]bool{"ADMIN": true},
+		ExpiresAt: time.Now().Unix() + 3600,
+	}
+
+	first, err := sprintutils.GenerateAuthToken(keyring, "", user, policy)
+	require.NoError(t, err)
+
+	second, err := sprintutils.GenerateAuthToken(keyring, "", user, policy)
+	require.NoError(t, err)
+
+	firstId, _, err := sprintutils.ParseAuthTokenId(first)
+	require.NoError(t, err)
+	secondId, _, err := sprintutils.ParseAuthTokenId(second)
+	require.NoError(t, err)
+	require.NotEqual(t, firstId, secondId)
+
+	actual, err := sprintutils.VerifyAuthToken(keyring, first, policy)
+	require.NoError(t, err)
+	require.Equal(t, "admin", actual.Username)
+	require.True(t, actual.Roles["ADMIN"])
+
+	_, err = sprintutils.VerifyAuthToken(keyring, first, &sprintutils.AuthTokenPolicy{Issuer: "sprint", Audience: "other"})
+	require.Error(t, err)
+
+	_, err = sprintutils.VerifyAuthToken(keyring, first, &sprintutils.AuthTokenPolicy{Issuer: "other", Audience: "sprint"})
+	require.Error(t, err)
+
+}
+
+func TestAuthKeyring(t *testing.T) {
+
+	user := &sprint.AuthorizedUser{
+		Username:  "admin",
+		ExpiresAt: time.Now().Unix() + 3600,
+	}
+
+	legacy, err := sprintutils.GenerateAuthToken(sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("legacy"))), "", user, nil)
+	require.NoError(t, err)
+
+	keyring := &sprintutils.AuthKeyring{
+		Active: "k2",
+		Keys: map[string]*sprintutils.AuthKey{
+			"":   sprintutils.HmacAuthKey([]byte("legacy")),
+			"k1": sprintutils.HmacAuthKey([]byte("first")),
+			"k2": sprintutils.HmacAuthKey([]byte("second")),
+		},
+	}
+
+	token, err := sprintutils.GenerateAuthToken(keyring, "", user, nil)
+	require.NoError(t, err)
+
+	_, err = sprintutils.VerifyAuthToken(keyring, token, nil)
+	require.NoError(t, err)
+
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, nil)
+	require.NoError(t, err)
+
+	delete(keyring.Keys, "")
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, nil)
+	require.Error(t, err)
+
+	delete(keyring.Keys, "k2")
+	_, err = sprintutils.VerifyAuthToken(keyring, token, nil)
+	require.Error(t, err)
+
+}
+
+func TestAsymmetricAuthKeys(t *testing.T) {
+
+	user := &sprint.AuthorizedUser{
+		Username:  "admin",
+		ExpiresAt: time.Now().Unix() + 3600,
+	}
+
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+
+		value, err := sprintutils.GenerateAuthKey(alg)
+		require.NoError(t, err)
+
+		key, err := sprintutils.ParseAuthKey(value)
+		require.NoError(t, err)
+		require.Equal(t, alg, key.Method.Alg())
+
+		keyring := sprintutils.SingleKeyring("k1", key)
+		token, err := sprintutils.GenerateAuthToken(keyring, "", user, nil)
+		require.NoError(t, err)
+
+		public := sprintutils.SingleKeyring("k1", &sprintutils.AuthKey{Method: key.Method, VerifyKey: key.VerifyKey})
+		_, err = sprintutils.VerifyAuthToken(public, token, nil)
+		require.NoError(t, err)
+
+		// keyring of the public key rejects HMAC tokens, so the public key can not be used as HMAC secret
+		hmac := sprintutils.SingleKeyring("k1", sprintutils.HmacAuthKey([]byte("secret")))
+		forged, err := sprintutils.GenerateAuthToken(hmac, "", user, nil)
+		require.NoError(t, err)
+		_, err = sprintutils.VerifyAuthToken(public, forged, nil)
+		require.Error(t, err)
+
+		jwks, err := keyring.JWKS()
+		require.NoError(t, err)
+		require.Contains(t, string(jwks), "\"kid\":\"k1\"")
+		require.Contains(t, string(jwks), alg)
+	}
+
+	jwks, err := sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("secret"))).JWKS()
+	require.NoError(t, err)
+	require.Equal(t, "{\"keys\":[]}", string(jwks))
+
+}
+
+func TestAuthTokenErrors(t *testing.T) {
+
+	keyring := sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("secret")))
+
+	expired, err := sprintutils.GenerateAuthToken(keyring, "", &sprint.AuthorizedUser{
+		Username:  "admin",
+		ExpiresAt: time.Now().Unix() - 3600,
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = sprintutils.VerifyAuthToken(keyring, expired, nil)
+	require.Equal(t, sprintutils.ErrTokenExpired, errors.Cause(err))
+	require.NotContains(t, err.Error(), expired)
+	require.Equal(t, "token is expired", sprintutils.AuthTokenErrorMessage(err))
+
+	valid, err := sprintutils.GenerateAuthToken(keyring, "", &sprint.AuthorizedUser{
+		Username:  "admin",
+		ExpiresAt: time.Now().Unix() + 3600,
+	}, nil)
+	require.NoError(t, err)
+
+	other := sprintutils.SingleKeyring("", sprintutils.HmacAuthKey([]byte("other")))
+	_, err = sprintutils.VerifyAuthToken(other, valid, nil)
+	require.Equal(t, sprintutils.ErrTokenSignature, errors.Cause(err))
+
+	parts := strings.Split(valid, ".")
+	_, err = sprintutils.VerifyAuthToken(keyring, parts[0] + ".e30." + parts[2], nil)
+	require.Equal(t, sprintutils.ErrTokenSignature, errors.Cause(err))
+
+	_, err = sprintutils.VerifyAuthToken(keyring, "not a token", nil)
+	require.Equal(t, sprintutils.ErrTokenMalformed, errors.Cause(err))
+	require.NotContains(t, err.Error(), "not a token")
+
+}
+
+func TestLegacyAuthToken(t *testing.T) {
+
+	secret := []byte("secret")
+	keyring := sprintutils.SingleKeyring("", sprintutils.HmacAuthKey(secret))
+
+	// token issued before JWT IDs, the username is in 'jti'
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &sprintutils.UserClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        "admin",
+			ExpiresAt: time.Now().Unix() + 3600,
+		},
+		Roles: []string{"ADMIN"},
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	policy := &sprintutils.AuthTokenPolicy{Issuer: "sprint", Audience: "sprint", Leeway: 60}
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, policy)
+	require.Equal(t, sprintutils.ErrTokenClaims, errors.Cause(err))
+
+	policy.LegacyUntil = time.Now().Unix() + 3600
+	user, err := sprintutils.VerifyAuthToken(keyring, legacy, policy)
+	require.NoError(t, err)
+	require.Equal(t, "admin", user.Username)
+	require.True(t, user.Roles["ADMIN"])
+
+	policy.LegacyUntil = time.Now().Unix() - 3600
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, policy)
+	require.Equal(t, sprintutils.ErrTokenClaims, errors.Cause(err))
+
+	policy.LegacyUntil = sprintutils.LegacyNoDeadline
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, policy)
+	require.NoError(t, err)
+
+	// legacy tokens are accepted only with the legacy key
+	keyring = sprintutils.SingleKeyring("k1", sprintutils.HmacAuthKey(secret))
+	policy.LegacyUntil = time.Now().Unix() + 3600
+	_, err = sprintutils.VerifyAuthToken(keyring, legacy, policy)
+	require.Equal(t, sprintutils.ErrTokenSignature, errors.Cause(err))
+}